@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yoooby/showtrack/internal/tmdb"
+)
+
+func calendarCommand(c *cli.Context) error {
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	shows, err := db.TMDBShows(!c.Bool("all"))
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+	if len(shows) == 0 {
+		return fail(c, "No followed shows are matched to TMDB yet.\nRun 'showtracker enrich' first.")
+	}
+
+	// Refresh catalogs, falling back to the cached ones when TMDB is unreachable
+	if !c.Bool("offline") {
+		client := newTMDBClient(db)
+		for _, show := range shows {
+			if _, err := tmdb.SyncCatalog(client, db, show.TMDBID); err != nil && !jsonOutput(c) {
+				fmt.Fprintf(os.Stderr, "⚠️  %s: using cached episode list: %v\n", show.Title, err)
+			}
+		}
+	}
+
+	entries, err := tmdb.BuildCalendar(db, shows, time.Now(), c.Int("recent"), c.Int("days"))
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+
+	if path := c.String("ics"); path != "" {
+		if path == "-" {
+			return writeICS(os.Stdout, entries, time.Now())
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return fail(c, "%v", err)
+		}
+		defer f.Close()
+		if err := writeICS(f, entries, time.Now()); err != nil {
+			return fail(c, "%v", err)
+		}
+		if jsonOutput(c) {
+			return printJSON(map[string]interface{}{"path": path, "events": len(entries)})
+		}
+		fmt.Printf("✅ Wrote %d episodes to %s\n", len(entries), path)
+		return nil
+	}
+
+	if jsonOutput(c) {
+		return printJSON(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("📅 Nothing aired recently or airing soon")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tSHOW\tEPISODE\tNAME\tSTATUS")
+	for _, e := range entries {
+		status := "upcoming"
+		if e.Aired {
+			status = "aired, not in library"
+		}
+		fmt.Fprintf(w, "%s\t%s\tS%02dE%02d\t%s\t%s\n",
+			e.Date.Format("2006-01-02"), e.Episode.Title, e.Episode.Season, e.Episode.Episode, e.Episode.Name, status)
+	}
+	return w.Flush()
+}
+
+// writeICS writes the calendar as an iCalendar file with one all-day event per episode.
+func writeICS(w io.Writer, entries []tmdb.CalendarEntry, now time.Time) error {
+	var b strings.Builder
+	line := func(s string) {
+		// lines longer than 75 octets are folded with a leading space
+		for len(s) > 75 {
+			cut := 75
+			for cut > 0 && !isRuneStart(s[cut]) {
+				cut--
+			}
+			b.WriteString(s[:cut] + "\r\n")
+			s = " " + s[cut:]
+		}
+		b.WriteString(s + "\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//showtrack//showtracker calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:ShowTracker")
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, e := range entries {
+		ep := e.Episode
+		summary := fmt.Sprintf("%s S%02dE%02d", ep.Title, ep.Season, ep.Episode)
+		if ep.Name != "" {
+			summary += " - " + ep.Name
+		}
+
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:tmdb-%d-s%de%d@showtracker", ep.TMDBShowID, ep.Season, ep.Episode))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + e.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + icsEscape(summary))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yoooby/showtrack/internal/model"
+	"github.com/yoooby/showtrack/internal/tmdb"
+)
+
+func TestWriteICS(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	entries := []tmdb.CalendarEntry{{
+		Episode: model.Episode{Title: "Law & Order", Season: 1, Episode: 2, TMDBShowID: 7,
+			Name: "Hello, World; a \\ test\nwith " + strings.Repeat("é", 40)},
+		Date: time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC),
+	}}
+
+	var b strings.Builder
+	if err := writeICS(&b, entries, now); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Errorf("not a calendar:\n%s", out)
+	}
+	for _, want := range []string{
+		"UID:tmdb-7-s1e2@showtracker\r\n",
+		"DTSTAMP:20240601T120000Z\r\n",
+		"DTSTART;VALUE=DATE:20240603\r\n",
+		"DTEND;VALUE=DATE:20240604\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+
+	for i, l := range strings.Split(out, "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("line %d is %d octets long", i, len(l))
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("line %d splits a character: %q", i, l)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	want := `SUMMARY:Law & Order S01E02 - Hello\, World\; a \\ test\nwith ` + strings.Repeat("é", 40) + "\r\n"
+	if !strings.Contains(unfolded, want) {
+		t.Errorf("missing %q in\n%s", want, unfolded)
+	}
+}
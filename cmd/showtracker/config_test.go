@@ -0,0 +1,26 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/db"
+)
+
+func TestConfigValuesHidesPassword(t *testing.T) {
+	store, err := db.InitDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if got := configValues(store)["vlc_password"]; got != "(default)" {
+		t.Errorf("vlc_password is %v without one set", got)
+	}
+	if err := store.SetSetting("vlc_password", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := configValues(store)["vlc_password"]; got != "(set)" {
+		t.Errorf("vlc_password is %v, want it hidden", got)
+	}
+}
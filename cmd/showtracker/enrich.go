@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+	"github.com/yoooby/showtrack/internal/tmdb"
+)
+
+// tmdbCacheTTL is how long TMDB responses are trusted before asking again.
+const tmdbCacheTTL = 7 * 24 * time.Hour
+
+func newTMDBClient(db *db.DB) *tmdb.Client {
+	return tmdb.NewClient(db.GetSetting("tmdb_api_key"), db.GetSetting("tmdb_base_url"), db.TMDBCache(tmdbCacheTTL))
+}
+
+func enrichCommand(c *cli.Context) error {
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	var shows []model.Show
+	if c.Args().Present() {
+		show, err := db.FindShow(strings.Join(c.Args().Slice(), " "))
+		if err != nil {
+			return fail(c, "%v", err)
+		}
+		shows = []model.Show{*show}
+	} else {
+		shows, err = db.Shows()
+		if err != nil {
+			return fail(c, "%v", err)
+		}
+	}
+
+	client := newTMDBClient(db)
+	results := []*tmdb.EnrichResult{}
+	failed := 0
+	for i := range shows {
+		show := &shows[i]
+		if !jsonOutput(c) {
+			fmt.Printf("🔍 Looking up %s...\n", show.Title)
+		}
+
+		res, err := tmdb.Enrich(client, db, show)
+		if err != nil {
+			failed++
+			if jsonOutput(c) {
+				results = append(results, &tmdb.EnrichResult{Title: show.Title, Errors: []string{err.Error()}})
+			} else {
+				fmt.Printf("❌ %s: %v\n", show.Title, err)
+			}
+			continue
+		}
+		results = append(results, res)
+
+		if !jsonOutput(c) {
+			fmt.Printf("✅ %s → %s (TMDB %d): %d episodes matched, %d not found\n",
+				show.Title, res.TMDBName, res.TMDBShowID, res.Matched, res.Unmatched)
+			if res.Mapped > 0 {
+				fmt.Printf("🔢 %d absolute numbered episodes placed in their seasons\n", res.Mapped)
+			}
+			for _, e := range res.Errors {
+				fmt.Printf("⚠️  %s\n", e)
+			}
+		}
+	}
+
+	if jsonOutput(c) {
+		printJSON(results)
+	}
+	if failed > 0 && failed == len(shows) {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
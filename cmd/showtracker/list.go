@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+func listCommand(c *cli.Context) error {
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	shows, err := db.ListShows(strings.Join(c.Args().Slice(), " "))
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+
+	if err := sortShows(shows, c.String("sort")); err != nil {
+		return fail(c, "%v", err)
+	}
+
+	if jsonOutput(c) {
+		if shows == nil {
+			shows = []model.ShowSummary{}
+		}
+		return printJSON(shows)
+	}
+
+	if len(shows) == 0 {
+		if c.Args().Present() {
+			return fail(c, "No show matching '%s'", strings.Join(c.Args().Slice(), " "))
+		}
+		return fail(c, "Library is empty. Try scanning your TV folder:\n  showtracker scan")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SHOW\tSEASONS\tEPISODES\tLAST WATCHED\tREMAINING\tWATCHED ON")
+	for _, s := range shows {
+		last, date := "-", "-"
+		if !s.LastWatched.IsZero() {
+			last = fmt.Sprintf("S%02dE%02d", s.LastSeason, s.LastEpisode)
+			if s.LastDate != "" {
+				last = s.LastDate
+			}
+			date = s.LastWatched.Local().Format("2006-01-02")
+		}
+		title := s.Title
+		if s.Year > 0 {
+			title = fmt.Sprintf("%s (%d)", s.Title, s.Year)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%s\n", title, s.Seasons, s.Episodes, last, s.Remaining, date)
+	}
+	return w.Flush()
+}
+
+func sortShows(shows []model.ShowSummary, by string) error {
+	switch by {
+	case "recent":
+		// Most recently watched first, never watched shows last
+		sort.SliceStable(shows, func(i, j int) bool {
+			if shows[i].LastWatched.Equal(shows[j].LastWatched) {
+				return shows[i].SortTitle < shows[j].SortTitle
+			}
+			return shows[i].LastWatched.After(shows[j].LastWatched)
+		})
+	case "name":
+		sort.SliceStable(shows, func(i, j int) bool {
+			if shows[i].SortTitle == shows[j].SortTitle {
+				return shows[i].Year < shows[j].Year
+			}
+			return shows[i].SortTitle < shows[j].SortTitle
+		})
+	case "remaining":
+		sort.SliceStable(shows, func(i, j int) bool {
+			if shows[i].Remaining == shows[j].Remaining {
+				return shows[i].SortTitle < shows[j].SortTitle
+			}
+			return shows[i].Remaining < shows[j].Remaining
+		})
+	default:
+		return fmt.Errorf("unknown sort order %q (use recent, name or remaining)", by)
+	}
+	return nil
+}
@@ -5,20 +5,34 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"github.com/yoooby/showtrack/internal/db"
 	"github.com/yoooby/showtrack/internal/model"
 	"github.com/yoooby/showtrack/internal/scan"
+	"github.com/yoooby/showtrack/internal/tmdb"
 	"github.com/yoooby/showtrack/internal/vlc"
 )
 
 func main() {
 	app := &cli.App{
 		Name:  "showtracker",
-		Usage: "Track and play TV shows with VLC",
+		Usage: "Track and play TV shows with VLC or mpv",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print machine-readable JSON instead of text",
+			},
+			&cli.BoolFlag{
+				Name:  "specials",
+				Usage: "Play the specials of a show, from the one after the last watched",
+			},
+		},
 		Commands: []*cli.Command{
 			{
 				Name:    "config",
@@ -36,9 +50,129 @@ func main() {
 						Aliases: []string{"f"},
 						Usage:   "Force full rescan",
 					},
+					&cli.BoolFlag{
+						Name:    "watch",
+						Aliases: []string{"w"},
+						Usage:   "Keep running and update the library as files are added, renamed or deleted",
+					},
+					&cli.BoolFlag{
+						Name:  "daemon",
+						Usage: "Watch in the background, detached from the terminal",
+					},
+					&cli.DurationFlag{
+						Name:  "settle",
+						Value: 10 * time.Second,
+						Usage: "How long files must stay untouched before a change is picked up",
+					},
+					&cli.StringFlag{
+						Name:  "log",
+						Value: defaultWatchLog(),
+						Usage: "Output file of the background watcher",
+					},
 				},
 				Action: scanCommand,
 			},
+			{
+				Name:   "roots",
+				Usage:  "List, add and remove the folders scanned for episodes",
+				Action: listRootsCommand,
+				Subcommands: []*cli.Command{
+					{
+						Name:      "add",
+						Usage:     "Add a folder to scan",
+						ArgsUsage: "PATH",
+						Flags:     rootFlags(),
+						Action:    addRootCommand,
+					},
+					{
+						Name:      "set",
+						Usage:     "Change the ignore patterns or offline behavior of a folder",
+						ArgsUsage: "PATH|ID",
+						Flags:     rootFlags(),
+						Action:    setRootCommand,
+					},
+					{
+						Name:      "enable",
+						Usage:     "Scan a folder again",
+						ArgsUsage: "PATH|ID",
+						Action:    func(c *cli.Context) error { return enableRootCommand(c, true) },
+					},
+					{
+						Name:      "disable",
+						Usage:     "Stop scanning a folder, keeping its episodes",
+						ArgsUsage: "PATH|ID",
+						Action:    func(c *cli.Context) error { return enableRootCommand(c, false) },
+					},
+					{
+						Name:      "remove",
+						Aliases:   []string{"rm"},
+						Usage:     "Forget a folder, keeping its episodes",
+						ArgsUsage: "PATH|ID",
+						Action:    removeRootCommand,
+					},
+				},
+			},
+			{
+				Name:      "list",
+				Aliases:   []string{"ls"},
+				Usage:     "List shows in the library with watching progress",
+				ArgsUsage: "[show name]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "sort",
+						Value: "recent",
+						Usage: "Sort order: recent, name or remaining",
+					},
+				},
+				Action: listCommand,
+			},
+			{
+				Name:      "enrich",
+				Usage:     "Fetch episode names, air dates and runtimes from TMDB",
+				ArgsUsage: "[show name]",
+				Action:    enrichCommand,
+			},
+			{
+				Name:      "numbering",
+				Usage:     "Show or set whether a show plays by season or by absolute episode number",
+				ArgsUsage: "SHOW [season|absolute]",
+				Action:    numberingCommand,
+			},
+			{
+				Name:      "missing",
+				Usage:     "Report episodes, seasons and specials missing from the library",
+				ArgsUsage: "[show name]",
+				Action:    missingCommand,
+			},
+			{
+				Name:  "calendar",
+				Usage: "List recently aired episodes not in the library and upcoming episodes",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "days",
+						Value: 7,
+						Usage: "Show episodes airing in the next `N` days",
+					},
+					&cli.IntFlag{
+						Name:  "recent",
+						Value: 7,
+						Usage: "Show episodes that aired in the last `N` days",
+					},
+					&cli.StringFlag{
+						Name:  "ics",
+						Usage: "Write an iCalendar file to `FILE` instead (- for stdout)",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Include every show matched to TMDB, not only the ones being watched",
+					},
+					&cli.BoolFlag{
+						Name:  "offline",
+						Usage: "Only use cached episode lists",
+					},
+				},
+				Action: calendarCommand,
+			},
 		},
 		Action: defaultAction, // When no command is specified
 	}
@@ -55,42 +189,124 @@ func initDB() (*db.DB, error) {
 		if savedPath := db.GetSetting("db_path"); savedPath != "" {
 			dbPath = savedPath
 		}
+		db.Close()
 	}
 	return db.InitDB(dbPath)
 }
 
-func ensureConfigured(db *db.DB) bool {
-	scanPath := db.GetSetting("scan_path")
-	if scanPath == "" {
-		fmt.Println("❌ ShowTracker is not configured yet.")
-		fmt.Println("Run 'showtracker config' to set up your TV shows folder.")
-		return false
+func ensureConfigured(c *cli.Context, db *db.DB) error {
+	roots, err := db.Roots()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	if len(roots) == 0 {
+		return fail(c, "ShowTracker is not configured yet.\nRun 'showtracker config' to set up your TV shows folder.")
 	}
 
 	if db.GetSetting("initial_scan") == "" {
-		fmt.Println("❌ Initial scan not completed.")
-		fmt.Println("Run 'showtracker scan' to scan your TV shows folder.")
-		return false
+		return fail(c, "Initial scan not completed.\nRun 'showtracker scan' to scan your TV shows folder.")
+	}
+
+	return nil
+}
+
+// configValues returns the current settings with their defaults applied.
+// The VLC password is only told to be set, like the TMDB API key.
+func configValues(db *db.DB) map[string]interface{} {
+	values := map[string]string{
+		"db_path":              db.GetSetting("db_path"),
+		"player":               db.GetSetting("player"),
+		"mpv_socket":           db.GetSetting("mpv_socket"),
+		"vlc_port":             db.GetSetting("vlc_port"),
+		"completion_threshold": db.GetSetting("completion_threshold"),
+		"specials":             db.SpecialsPlacement(),
+		"tmdb_base_url":        db.GetSetting("tmdb_base_url"),
+	}
+	defaults := map[string]string{
+		"db_path":              "db.sqlite3",
+		"player":               "vlc",
+		"mpv_socket":           vlc.DefaultMPVSocket(),
+		"vlc_port":             "42069",
+		"completion_threshold": "90",
+		"tmdb_base_url":        tmdb.DefaultBaseURL,
+	}
+	result := map[string]interface{}{}
+	for k, v := range values {
+		if v == "" {
+			v = defaults[k]
+		}
+		result[k] = v
+	}
+	result["vlc_password"] = "(default)"
+	if db.GetSetting("vlc_password") != "" {
+		result["vlc_password"] = "(set)"
 	}
 
-	return true
+	roots, _ := db.Roots()
+	if roots == nil {
+		roots = []model.LibraryRoot{}
+	}
+	result["roots"] = roots
+	return result
+}
+
+// newBackend creates the media player selected by the player setting.
+func newBackend(db *db.DB) (vlc.MediaPlayer, error) {
+	switch player := db.GetSetting("player"); player {
+	case "", "vlc":
+		// Get VLC settings from database
+		password := db.GetSetting("vlc_password")
+		if password == "" {
+			password = "zebi"
+		}
+
+		portStr := db.GetSetting("vlc_port")
+		port := 42069
+		if portStr != "" {
+			if p, err := strconv.Atoi(portStr); err == nil {
+				port = p
+			}
+		}
+
+		return &vlc.VLC{Host: "127.0.0.1", Port: port, Password: password}, nil
+	case "mpv":
+		socket := db.GetSetting("mpv_socket")
+		if socket == "" {
+			socket = vlc.DefaultMPVSocket()
+		}
+		return &vlc.MPV{SocketPath: socket}, nil
+	default:
+		return nil, fmt.Errorf("unknown player %q (use vlc or mpv)", player)
+	}
 }
 
 func configCommand(c *cli.Context) error {
 	db, err := initDB()
 	if err != nil {
-		return fmt.Errorf("database error: %v", err)
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	// Configuration is interactive, JSON mode only reports the current values
+	if jsonOutput(c) {
+		return printJSON(configValues(db))
 	}
 
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println("=== ShowTracker Configuration ===")
 
-	// Configure TV Shows Path
-	currentPath := db.GetSetting("scan_path")
-	if currentPath != "" {
-		fmt.Printf("Current TV shows folder: %s\n", currentPath)
-		fmt.Print("Enter new path (or press Enter to keep current): ")
+	// Configure TV Shows Paths, 'showtracker roots' manages the rest
+	roots, err := db.Roots()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	if len(roots) > 0 {
+		fmt.Println("Current TV shows folders:")
+		for _, r := range roots {
+			fmt.Printf("  %s\n", r.Path)
+		}
+		fmt.Print("Enter another folder to add (or press Enter to keep current): ")
 	} else {
 		fmt.Print("Enter path to your TV shows folder: ")
 	}
@@ -110,19 +326,24 @@ func configCommand(c *cli.Context) error {
 			}
 		}
 
-		db.SetSetting("scan_path", input)
-		fmt.Printf("✅ TV shows path set to: %s\n", input)
+		root := &model.LibraryRoot{Path: input, Enabled: true}
+		if err := db.AddRoot(root); err != nil {
+			return fail(c, "%v", err)
+		}
+		fmt.Printf("✅ TV shows folder added: %s\n", root.Path)
 
 		// Ask if they want to scan now
 		fmt.Print("Scan this folder now? (y/n): ")
 		scanNow, _ := reader.ReadString('\n')
 		if strings.ToLower(strings.TrimSpace(scanNow)) == "y" {
-			performScan(input, db)
+			if _, err := performScan(c, *root, db); err != nil {
+				return fail(c, "Error scanning folder: %v", err)
+			}
 		} else {
 			fmt.Println("Remember to run 'showtracker scan' before playing episodes.")
 		}
-	} else if currentPath != "" {
-		fmt.Printf("✅ Keeping current path: %s\n", currentPath)
+	} else if len(roots) > 0 {
+		fmt.Println("✅ Keeping current folders")
 	}
 
 	// Configure Database Path
@@ -145,6 +366,29 @@ func configCommand(c *cli.Context) error {
 		fmt.Printf("✅ Keeping current database: %s\n", currentDB)
 	}
 
+	// Configure Player
+	fmt.Println("\n--- Player Settings ---")
+
+	currentPlayer := db.GetSetting("player")
+	if currentPlayer == "" {
+		currentPlayer = "vlc"
+	}
+	fmt.Printf("Current player: %s\n", currentPlayer)
+	fmt.Print("Enter player to use, vlc or mpv (or press Enter to keep current): ")
+
+	playerInput, _ := reader.ReadString('\n')
+	playerInput = strings.ToLower(strings.TrimSpace(playerInput))
+	if playerInput != "" { // Only change if user entered something
+		if playerInput != "vlc" && playerInput != "mpv" {
+			fmt.Println("❌ Player must be vlc or mpv")
+		} else {
+			db.SetSetting("player", playerInput)
+			fmt.Printf("✅ Player set to: %s\n", playerInput)
+		}
+	} else {
+		fmt.Printf("✅ Keeping current player: %s\n", currentPlayer)
+	}
+
 	// Configure VLC Settings
 	fmt.Println("\n--- VLC Settings ---")
 
@@ -184,86 +428,299 @@ func configCommand(c *cli.Context) error {
 		fmt.Printf("✅ Keeping current port: %s\n", currentPort)
 	}
 
+	// Configure TMDB
+	fmt.Println("\n--- TMDB Settings ---")
+
+	if db.GetSetting("tmdb_api_key") != "" {
+		fmt.Println("Current TMDB API key: (set)")
+	} else {
+		fmt.Println("Current TMDB API key: (none, episode metadata is disabled)")
+	}
+	fmt.Print("Enter TMDB API key or read access token (or press Enter to keep current): ")
+
+	keyInput, _ := reader.ReadString('\n')
+	keyInput = strings.TrimSpace(keyInput)
+	if keyInput != "" { // Only change if user entered something
+		db.SetSetting("tmdb_api_key", keyInput)
+		fmt.Println("✅ TMDB API key saved")
+	}
+
+	currentBaseURL := db.GetSetting("tmdb_base_url")
+	if currentBaseURL == "" {
+		currentBaseURL = tmdb.DefaultBaseURL
+	}
+	fmt.Printf("Current TMDB API URL: %s\n", currentBaseURL)
+	fmt.Print("Enter new TMDB API URL (or press Enter to keep current): ")
+
+	baseURLInput, _ := reader.ReadString('\n')
+	baseURLInput = strings.TrimSpace(baseURLInput)
+	if baseURLInput != "" { // Only change if user entered something
+		db.SetSetting("tmdb_base_url", baseURLInput)
+		fmt.Printf("✅ TMDB API URL set to: %s\n", baseURLInput)
+	} else {
+		fmt.Printf("✅ Keeping current TMDB API URL: %s\n", currentBaseURL)
+	}
+
+	// Configure Playback Settings
+	fmt.Println("\n--- Playback Settings ---")
+
+	currentThreshold := db.GetSetting("completion_threshold")
+	if currentThreshold == "" {
+		currentThreshold = "90"
+	}
+	fmt.Printf("Current completion threshold: %s%%\n", currentThreshold)
+	fmt.Print("Enter percentage of an episode that counts as watched (or press Enter to keep current): ")
+
+	thresholdInput, _ := reader.ReadString('\n')
+	thresholdInput = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(thresholdInput), "%"))
+	if thresholdInput != "" { // Only change if user entered something
+		if v, err := strconv.Atoi(thresholdInput); err != nil || v <= 0 || v > 100 {
+			fmt.Println("❌ Threshold must be a number between 1 and 100")
+		} else {
+			db.SetSetting("completion_threshold", thresholdInput)
+			fmt.Printf("✅ Completion threshold set to: %s%%\n", thresholdInput)
+		}
+	} else {
+		fmt.Printf("✅ Keeping current threshold: %s%%\n", currentThreshold)
+	}
+
+	currentSpecials := db.SpecialsPlacement()
+	fmt.Printf("Current specials: %s\n", currentSpecials)
+	fmt.Print("Enter skip to leave specials out or aired to play them where they aired (or press Enter to keep current): ")
+
+	specialsInput, _ := reader.ReadString('\n')
+	specialsInput = strings.ToLower(strings.TrimSpace(specialsInput))
+	if specialsInput != "" { // Only change if user entered something
+		if specialsInput != model.SpecialsSkip && specialsInput != model.SpecialsAired {
+			fmt.Println("❌ Specials must be skip or aired")
+		} else {
+			db.SetSetting("specials", specialsInput)
+			fmt.Printf("✅ Specials set to: %s\n", specialsInput)
+		}
+	} else {
+		fmt.Printf("✅ Keeping current specials: %s\n", currentSpecials)
+	}
+
 	fmt.Println("\n🎉 Configuration complete!")
 	return nil
 }
 
-func performScan(path string, db *db.DB) {
-	fmt.Printf("🔍 Scanning folder: %s\n", path)
+// scanResult is the JSON shape of the scan of one library root.
+type scanResult struct {
+	Path    string   `json:"path"`
+	Full    bool     `json:"full"`
+	Offline bool     `json:"offline"` // not mounted, its episodes are unavailable
+	Error   string   `json:"error,omitempty"`
+	Files   int      `json:"files"`
+	Found   int      `json:"found"`
+	Saved   int      `json:"saved"`
+	Errors  []string `json:"errors"`
+
+	Added   []model.Episode `json:"added"`
+	Removed []model.Episode `json:"removed"`
+	Moved   []db.Move       `json:"moved"`
+}
+
+// offlineResult is the scan of a root that isn't mounted.
+func offlineResult(path string) *scanResult {
+	return &scanResult{Path: path, Offline: true, Errors: []string{},
+		Added: []model.Episode{}, Removed: []model.Episode{}, Moved: []db.Move{}}
+}
+
+func performScan(c *cli.Context, root model.LibraryRoot, db *db.DB) (*scanResult, error) {
+	if root.OfflineWhenUnmounted && scan.Unmounted(root.Path) {
+		// don't prune a drive that is just unplugged
+		n, err := db.SetRootOffline(root.Path, true)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonOutput(c) {
+			fmt.Printf("💤 %s is not mounted, %d more episodes marked unavailable\n", root.Path, n)
+		}
+		return offlineResult(root.Path), nil
+	}
+
+	if !jsonOutput(c) {
+		fmt.Printf("🔍 Scanning folder: %s\n", root.Path)
+	}
 
-	episodes, err := scan.ScanFolder(path, db)
+	res, err := scan.ScanFolder(root.Path, root.Ignore, db)
 	if err != nil {
-		fmt.Printf("❌ Error scanning folder: %v\n", err)
-		return
+		return nil, err
+	}
+
+	result := &scanResult{Path: root.Path, Files: len(res.Files), Found: len(res.Episodes), Errors: []string{}}
+	for _, e := range res.Errors {
+		result.Errors = append(result.Errors, e.Error())
 	}
 
-	fmt.Printf("📺 Found %d episodes\n", len(episodes))
+	if !jsonOutput(c) {
+		fmt.Printf("📺 %d video files, found %d episodes in new or changed ones\n", len(res.Files), len(res.Episodes))
+		for _, e := range result.Errors {
+			fmt.Printf("⚠️  %s\n", e)
+		}
+	}
 
-	err = db.SaveEpisodes(episodes)
+	changes, err := db.SyncEpisodes(&res.ScannedTree)
 	if err != nil {
-		fmt.Printf("❌ Error saving episodes: %v\n", err)
-		return
+		return nil, fmt.Errorf("failed to save episodes: %w", err)
+	}
+	result.Saved = len(res.Episodes)
+	result.Added = changes.Added
+	result.Removed = changes.Removed
+	result.Moved = changes.Moved
+	result.Errors = append(result.Errors, changes.Conflicts...)
+
+	if !jsonOutput(c) {
+		fmt.Printf("➕ %d added, ➖ %d removed, 🔀 %d moved\n", len(changes.Added), len(changes.Removed), len(changes.Moved))
+		for _, e := range changes.Conflicts {
+			fmt.Printf("⚠️  %s\n", e)
+		}
+		for _, ep := range changes.Removed {
+			fmt.Printf("  ➖ %s %s (%s)\n", ep.Title, ep.Code(), ep.Path)
+		}
+		for _, m := range changes.Moved {
+			fmt.Printf("  🔀 %s %s: %s → %s\n", m.Episode.Title, m.Episode.Code(), m.From, m.Episode.Path)
+		}
+		for _, ep := range changes.Added {
+			if ep.Confidence < scan.LowConfidence {
+				fmt.Printf("  ❓ %s %s is a guess (%.0f%% sure): %s\n", ep.Title, ep.Code(), ep.Confidence*100, ep.Path)
+			}
+		}
 	}
 
 	db.SetSetting("initial_scan", "completed")
-	fmt.Println("✅ Scan completed successfully!")
+	if !jsonOutput(c) {
+		fmt.Println("✅ Scan completed successfully!")
+	}
+	return result, nil
 }
 
 func scanCommand(c *cli.Context) error {
 	db, err := initDB()
 	if err != nil {
-		return fmt.Errorf("database error: %v", err)
+		return fail(c, "database error: %v", err)
 	}
+	defer db.Close()
 
-	scanPath := db.GetSetting("scan_path")
-	if scanPath == "" {
-		fmt.Println("❌ No TV shows path configured.")
-		fmt.Println("Run 'showtracker config' first to set your TV shows folder.")
-		return nil
+	roots, err := enabledRoots(db)
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	if len(roots) == 0 {
+		return fail(c, "No TV shows folder configured.\nRun 'showtracker config' or 'showtracker roots add PATH' first.")
 	}
 
-	if !c.Bool("force") && db.GetSetting("initial_scan") != "" {
-		fmt.Println("🔄 Performing scan...")
-	} else {
-		fmt.Println("🔄 Performing full scan...")
-		db.Conn.Exec("DELETE FROM folder_hashes")
-		db.Conn.Exec("DELETE FROM episodes")
+	if c.Bool("daemon") {
+		return startDaemon(c)
+	}
+
+	full := c.Bool("force") || db.GetSetting("initial_scan") == ""
+	if !jsonOutput(c) {
+		if full {
+			fmt.Println("🔄 Performing full scan...")
+		} else {
+			fmt.Println("🔄 Performing scan...")
+		}
+	}
+	if full {
+		// episodes are kept, the scan prunes the ones that are gone
+		if _, err := db.Conn.Exec("DELETE FROM files"); err != nil {
+			return fail(c, "failed to forget the scanned files: %v", err)
+		}
+	}
+
+	// a root that fails doesn't keep the others from being scanned
+	results := []*scanResult{}
+	failed := false
+	for _, root := range roots {
+		result, err := performScan(c, root, db)
+		if err != nil {
+			failed = true
+			result = &scanResult{Path: root.Path, Error: err.Error()}
+			if !jsonOutput(c) {
+				fmt.Fprintf(os.Stderr, "❌ Error scanning %s: %v\n", root.Path, err)
+			}
+		}
+		result.Full = full
+		results = append(results, result)
+	}
+	if jsonOutput(c) {
+		printJSON(results)
 	}
 
-	performScan(scanPath, db)
+	if c.Bool("watch") {
+		return watchLibrary(c, db, roots)
+	}
+	if failed {
+		return cli.Exit("", 1)
+	}
 	return nil
 }
 
 func defaultAction(c *cli.Context) error {
 	db, err := initDB()
 	if err != nil {
-		return fmt.Errorf("database error: %v", err)
+		return fail(c, "database error: %v", err)
 	}
+	defer db.Close()
 
 	// Check if configured
-	if !ensureConfigured(db) {
-		return nil
+	if err := ensureConfigured(c, db); err != nil {
+		return err
 	}
 
 	// Parse arguments for episode selection
 	var episode model.Episode
 	args := c.Args().Slice()
 
+	if c.Bool("specials") && len(args) != 1 && len(args) != 3 {
+		return fail(c, "--specials needs a show name")
+	}
+
 	switch len(args) {
 	case 0:
 		// Play latest watched episode globally
 		ep, err := db.FindLatestWatchedEpisodeGlobal()
 		if err != nil {
-			fmt.Println("❌ No episodes found. Try scanning your TV folder:")
-			fmt.Println("  showtracker scan")
-			return nil
+			return fail(c, "No episodes found. Try scanning your TV folder:\n  showtracker scan")
 		}
 		episode = *ep
 	case 1:
+		if c.Bool("specials") {
+			// Play the next special of a show
+			show, err := db.FindShow(args[0])
+			if err != nil {
+				return fail(c, "show not found: %v", err)
+			}
+			ep, err := db.NextSpecial(show.ID)
+			if err != nil {
+				return fail(c, "%s: %v", show.Title, err)
+			}
+			episode = *ep
+			break
+		}
+
 		// Play latest episode of specific show
 		ep, err := db.FindLatestWatchedEpisode(args[0])
 		if err != nil {
-			return fmt.Errorf("show not found: %v", err)
+			return fail(c, "show not found: %v", err)
+		}
+		episode = *ep
+	case 2:
+		// Play the episode of a daily show that aired on a date
+		date, err := time.Parse("2006-01-02", args[1])
+		if err != nil {
+			return fail(c, "date must be YYYY-MM-DD")
+		}
+
+		show, err := db.FindShow(args[0])
+		if err != nil {
+			return fail(c, "show not found: %v", err)
+		}
+		ep, err := db.GetEpisodeByDate(show.ID, date.Format("2006-01-02"))
+		if err != nil {
+			return fail(c, "%s: %v", show.Title, err)
 		}
 		episode = *ep
 	case 3:
@@ -271,43 +728,58 @@ func defaultAction(c *cli.Context) error {
 		season, err1 := strconv.Atoi(args[1])
 		episodeNum, err2 := strconv.Atoi(args[2])
 		if err1 != nil || err2 != nil {
-			return fmt.Errorf("season and episode must be integers")
+			return fail(c, "season and episode must be integers")
 		}
 
-		ep, err := db.GetEpisode(args[0], season, episodeNum)
+		show, err := db.FindShow(args[0])
 		if err != nil {
-			return fmt.Errorf("episode not found: %v", err)
+			return fail(c, "show not found: %v", err)
+		}
+		ep, err := db.GetEpisode(show.ID, season, episodeNum)
+		if err != nil {
+			return fail(c, "%s: %v", show.Title, err)
 		}
 		episode = *ep
 	default:
+		if jsonOutput(c) {
+			return fail(c, "expected no arguments, a show name, a show name with a date, or a show name with season and episode")
+		}
 		fmt.Println("Usage:")
 		fmt.Println("  showtracker                           # Play latest watched episode")
 		fmt.Println("  showtracker \"Show Name\"               # Play latest episode of show")
 		fmt.Println("  showtracker \"Show Name\" <season> <episode>  # Play specific episode")
+		fmt.Println("  showtracker \"Show Name\" YYYY-MM-DD    # Play episode of a daily show")
+		fmt.Println("  showtracker --specials \"Show Name\"    # Play the show's specials")
 		fmt.Println("  showtracker config                    # Configure settings")
 		fmt.Println("  showtracker scan                      # Rescan TV folder")
-		return nil
+		fmt.Println("  showtracker roots                     # List, add and remove TV folders")
+		fmt.Println("  showtracker list                      # List shows and progress")
+		fmt.Println("  showtracker enrich                    # Fetch episode metadata from TMDB")
+		fmt.Println("  showtracker missing \"Show Name\"       # Report missing episodes")
+		fmt.Println("  showtracker numbering \"Show\" absolute # Play a show by absolute number")
+		fmt.Println("  showtracker calendar                  # Recent and upcoming episodes")
+		return cli.Exit("", 1)
 	}
 
-	fmt.Printf("▶️  Playing: %s S%02dE%02d\n", episode.Title, episode.Season, episode.Episode)
-
-	// Get VLC settings from database
-	password := db.GetSetting("vlc_password")
-	if password == "" {
-		password = "zebi"
+	if jsonOutput(c) {
+		printJSON(map[string]interface{}{"playing": episode})
+	} else {
+		fmt.Printf("▶️  Playing: %s %s\n", episode.Title, episode.Code())
 	}
 
-	portStr := db.GetSetting("vlc_port")
-	port := 42069
-	if portStr != "" {
-		if p, err := strconv.Atoi(portStr); err == nil {
-			port = p
-		}
+	backend, err := newBackend(db)
+	if err != nil {
+		return fail(c, "%v", err)
 	}
 
-	player := vlc.NewPlayer(password, port, *db)
-	player.PlayShow(episode)
+	// Ctrl+C or a kill stops playback after saving the final position
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Keep program running
-	select {}
+	player := vlc.NewPlayer(backend, *db)
+	player.SpecialsOnly = c.Bool("specials")
+	if err := player.PlayShow(ctx, episode); err != nil {
+		return fail(c, "%v", err)
+	}
+	return nil
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yoooby/showtrack/internal/model"
+	"github.com/yoooby/showtrack/internal/tmdb"
+)
+
+func missingCommand(c *cli.Context) error {
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	var shows []model.Show
+	if c.Args().Present() {
+		show, err := db.FindShow(strings.Join(c.Args().Slice(), " "))
+		if err != nil {
+			return fail(c, "%v", err)
+		}
+		shows = []model.Show{*show}
+	} else {
+		shows, err = db.Shows()
+		if err != nil {
+			return fail(c, "%v", err)
+		}
+	}
+
+	client := newTMDBClient(db)
+	reports := []*tmdb.MissingReport{}
+	for i := range shows {
+		show := &shows[i]
+		report, err := tmdb.FindMissing(client, db, show)
+		if err != nil {
+			if len(shows) == 1 {
+				return fail(c, "%s: %v", show.Title, err)
+			}
+			if !jsonOutput(c) {
+				fmt.Printf("❌ %s: %v\n", show.Title, err)
+			}
+			continue
+		}
+		reports = append(reports, report)
+
+		if !jsonOutput(c) {
+			printMissing(report)
+		}
+	}
+
+	if jsonOutput(c) {
+		return printJSON(reports)
+	}
+	return nil
+}
+
+func printMissing(r *tmdb.MissingReport) {
+	fmt.Printf("📺 %s (%s, TMDB %d)\n", r.Title, r.TMDBName, r.TMDBShowID)
+	if r.Empty() {
+		fmt.Println("  ✅ Nothing missing")
+		return
+	}
+
+	if len(r.Missing) > 0 {
+		fmt.Printf("  Missing episodes: %s\n", episodeCodes(r.Missing))
+	}
+	if len(r.MissingSeasons) > 0 {
+		seasons := make([]string, len(r.MissingSeasons))
+		for i, s := range r.MissingSeasons {
+			seasons[i] = fmt.Sprintf("S%02d", s)
+		}
+		fmt.Printf("  Seasons not downloaded: %s\n", strings.Join(seasons, ", "))
+	}
+	if len(r.Specials) > 0 {
+		fmt.Printf("  Specials not in library: %s\n", episodeCodes(r.Specials))
+	}
+}
+
+func episodeCodes(eps []model.Episode) string {
+	codes := make([]string, len(eps))
+	for i, ep := range eps {
+		codes[i] = fmt.Sprintf("S%02dE%02d", ep.Season, ep.Episode)
+	}
+	return strings.Join(codes, ", ")
+}
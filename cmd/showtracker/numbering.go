@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+func numberingCommand(c *cli.Context) error {
+	if c.NArg() < 1 || c.NArg() > 2 {
+		return fail(c, "usage: showtracker numbering SHOW [season|absolute]")
+	}
+
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	show, err := db.FindShow(c.Args().Get(0))
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+
+	if c.NArg() == 2 {
+		if err := db.SetNumbering(show.ID, c.Args().Get(1)); err != nil {
+			return fail(c, "%v", err)
+		}
+		show.Numbering = c.Args().Get(1)
+	}
+
+	numbering := show.Numbering
+	if numbering == "" {
+		numbering = model.SeasonNumbering
+	}
+	if jsonOutput(c) {
+		return printJSON(map[string]interface{}{"show": show, "numbering": numbering})
+	}
+	if numbering == model.AbsoluteNumbering {
+		fmt.Printf("🔢 %s plays by absolute episode number\n", show.Title)
+	} else {
+		fmt.Printf("📅 %s plays by season and episode\n", show.Title)
+	}
+	return nil
+}
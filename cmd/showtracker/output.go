@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// jsonOutput reports whether the global --json flag is set.
+func jsonOutput(c *cli.Context) bool {
+	return c.Bool("json")
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// fail reports an error in the selected output format and makes showtracker
+// exit with a non-zero code.
+func fail(c *cli.Context, format string, a ...interface{}) error {
+	msg := fmt.Sprintf(format, a...)
+	if jsonOutput(c) {
+		printJSON(map[string]string{"error": msg})
+		return cli.Exit("", 1)
+	}
+	return cli.Exit("❌ "+msg, 1)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// rootFlags are the folder settings shared by roots add and set.
+func rootFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "ignore",
+			Usage: "Skip files and folders matching `PATTERN`, by name or path relative to the folder (repeatable)",
+		},
+		&cli.BoolFlag{
+			Name:  "offline-when-unmounted",
+			Usage: "When the folder is missing or empty, mark its episodes unavailable instead of removed",
+		},
+	}
+}
+
+// applyRootFlags copies the flags that were given onto r.
+func applyRootFlags(c *cli.Context, r *model.LibraryRoot) {
+	if c.IsSet("ignore") {
+		r.Ignore = nil
+		for _, p := range c.StringSlice("ignore") {
+			if p = strings.TrimSpace(p); p != "" {
+				r.Ignore = append(r.Ignore, p)
+			}
+		}
+	}
+	if c.IsSet("offline-when-unmounted") {
+		r.OfflineWhenUnmounted = c.Bool("offline-when-unmounted")
+	}
+}
+
+// enabledRoots returns the library roots a scan goes through.
+func enabledRoots(db *db.DB) ([]model.LibraryRoot, error) {
+	roots, err := db.Roots()
+	if err != nil {
+		return nil, err
+	}
+	var enabled []model.LibraryRoot
+	for _, r := range roots {
+		if r.Enabled {
+			enabled = append(enabled, r)
+		}
+	}
+	return enabled, nil
+}
+
+func listRootsCommand(c *cli.Context) error {
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	roots, err := db.Roots()
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+
+	if jsonOutput(c) {
+		if roots == nil {
+			roots = []model.LibraryRoot{}
+		}
+		return printJSON(roots)
+	}
+
+	if len(roots) == 0 {
+		fmt.Println("No TV shows folders yet, add one with 'showtracker roots add PATH'.")
+		return nil
+	}
+	for _, r := range roots {
+		status := "✅"
+		if !r.Enabled {
+			status = "⏸️ "
+		}
+		fmt.Printf("%s %d. %s\n", status, r.ID, r.Path)
+		if r.OfflineWhenUnmounted {
+			fmt.Println("     💤 offline when unmounted")
+		}
+		if len(r.Ignore) > 0 {
+			fmt.Printf("     🙈 ignoring %s\n", strings.Join(r.Ignore, ", "))
+		}
+	}
+	return nil
+}
+
+func addRootCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fail(c, "usage: showtracker roots add PATH")
+	}
+
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	path, err := filepath.Abs(c.Args().First())
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+	root := &model.LibraryRoot{Path: path, Enabled: true}
+	applyRootFlags(c, root)
+	if _, err := os.Stat(root.Path); err != nil && !root.OfflineWhenUnmounted {
+		return fail(c, "%v", err)
+	}
+	if err := db.AddRoot(root); err != nil {
+		return fail(c, "%v", err)
+	}
+
+	if jsonOutput(c) {
+		return printJSON(root)
+	}
+	fmt.Printf("✅ Added %s, run 'showtracker scan' to pick up its episodes\n", root.Path)
+	return nil
+}
+
+func setRootCommand(c *cli.Context) error {
+	return updateRoot(c, func(r *model.LibraryRoot) { applyRootFlags(c, r) })
+}
+
+func enableRootCommand(c *cli.Context, enabled bool) error {
+	return updateRoot(c, func(r *model.LibraryRoot) { r.Enabled = enabled })
+}
+
+// updateRoot changes the root named by the first argument and saves it.
+func updateRoot(c *cli.Context, change func(*model.LibraryRoot)) error {
+	if c.NArg() != 1 {
+		return fail(c, "expected a folder path or id")
+	}
+
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	root, err := db.FindRoot(c.Args().First())
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+	change(root)
+	if err := db.UpdateRoot(root); err != nil {
+		return fail(c, "%v", err)
+	}
+
+	if jsonOutput(c) {
+		return printJSON(root)
+	}
+	fmt.Printf("✅ Updated %s\n", root.Path)
+	return nil
+}
+
+func removeRootCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fail(c, "expected a folder path or id")
+	}
+
+	db, err := initDB()
+	if err != nil {
+		return fail(c, "database error: %v", err)
+	}
+	defer db.Close()
+
+	root, err := db.FindRoot(c.Args().First())
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+	if err := db.RemoveRoot(root.ID); err != nil {
+		return fail(c, "%v", err)
+	}
+
+	if jsonOutput(c) {
+		return printJSON(root)
+	}
+	fmt.Printf("🗑️  Removed %s, its episodes stay in the library\n", root.Path)
+	return nil
+}
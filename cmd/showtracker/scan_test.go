@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+func TestScanKeepsUnmountedRoot(t *testing.T) {
+	store, err := db.InitDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	c := cli.NewContext(cli.NewApp(), flag.NewFlagSet("scan", flag.ContinueOnError), nil)
+
+	root := model.LibraryRoot{Path: t.TempDir(), Enabled: true, OfflineWhenUnmounted: true}
+	video := filepath.Join(root.Path, "Lost", "Lost.S01E01.mkv")
+	if err := os.MkdirAll(filepath.Dir(video), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(video, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := performScan(c, root, store); err != nil || len(res.Added) != 1 {
+		t.Fatalf("first scan: %+v, %v", res, err)
+	}
+	show, err := store.FindShow("Lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the drive is unplugged, leaving an empty mount point
+	if err := os.RemoveAll(filepath.Join(root.Path, "Lost")); err != nil {
+		t.Fatal(err)
+	}
+	res, err := performScan(c, root, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Offline || len(res.Removed) != 0 {
+		t.Errorf("expected the root offline and nothing removed, got %+v", res)
+	}
+	if _, err := store.GetEpisode(show.ID, 1, 1); err == nil {
+		t.Error("the episode is still playable")
+	}
+
+	// and plugged back in
+	if err := os.MkdirAll(filepath.Dir(video), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(video, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := performScan(c, root, store); err != nil || res.Offline {
+		t.Fatalf("scan after remounting: %+v, %v", res, err)
+	}
+	if _, err := store.GetEpisode(show.ID, 1, 1); err != nil {
+		t.Errorf("the episode is still unavailable: %v", err)
+	}
+}
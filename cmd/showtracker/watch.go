@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+	"github.com/yoooby/showtrack/internal/scan"
+)
+
+// defaultWatchLog is where a watch daemon writes its output without --log.
+func defaultWatchLog() string {
+	return filepath.Join(os.TempDir(), "showtracker-watch.log")
+}
+
+// rootRetryInterval is how often a root that can't be watched, like an
+// unplugged drive, is tried again.
+const rootRetryInterval = 30 * time.Second
+
+// watchLibrary rescans a root whenever video files change under it, until
+// interrupted. Roots that can't be watched are tried again every
+// rootRetryInterval, and scanned once they can.
+func watchLibrary(c *cli.Context, db *db.DB, roots []model.LibraryRoot) error {
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// one scan at a time, they all write to the same database
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, root := range roots {
+		if !jsonOutput(c) {
+			fmt.Printf("👀 Watching %s for changes (Ctrl+C to stop)...\n", root.Path)
+		}
+		rescan := func() {
+			mu.Lock()
+			defer mu.Unlock()
+			result, err := performScan(c, root, db)
+			if err != nil {
+				if jsonOutput(c) {
+					printJSON(&scanResult{Path: root.Path, Error: err.Error()})
+				} else {
+					fmt.Fprintf(os.Stderr, "❌ Error scanning %s: %v\n", root.Path, err)
+				}
+				return
+			}
+			if jsonOutput(c) {
+				printJSON(result)
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := scan.Watch(ctx, root.Path, c.Duration("settle"), rescan)
+				if err == nil || ctx.Err() != nil {
+					return
+				}
+				log.Printf("Not watching %s, trying again in %s: %v", root.Path, rootRetryInterval, err)
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(rootRetryInterval):
+					}
+					if !scan.Unmounted(root.Path) {
+						break
+					}
+				}
+				// catch up with whatever changed while it was away
+				rescan()
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// startDaemon runs 'scan --watch' in the background, detached from the
+// terminal, with its output going to the --log file.
+func startDaemon(c *cli.Context) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+
+	var args []string
+	if jsonOutput(c) {
+		args = append(args, "--json")
+	}
+	args = append(args, "scan", "--watch", "--settle", c.Duration("settle").String())
+	if c.Bool("force") {
+		args = append(args, "--force")
+	}
+
+	logPath := c.String("log")
+	out, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fail(c, "%v", err)
+	}
+	defer out.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	detachDaemon(cmd)
+	if err := cmd.Start(); err != nil {
+		return fail(c, "failed to start watcher: %v", err)
+	}
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+
+	if jsonOutput(c) {
+		return printJSON(map[string]interface{}{"pid": pid, "log": logPath})
+	}
+	fmt.Printf("👀 Watching in the background (pid %d), logging to %s\n", pid, logPath)
+	return nil
+}
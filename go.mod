@@ -3,6 +3,7 @@ module github.com/yoooby/showtrack
 go 1.25.1
 
 require (
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/mattn/go-sqlite3 v1.14.32
 	github.com/razsteinmetz/go-ptn v1.0.0
 	github.com/urfave/cli/v2 v2.27.7
@@ -12,5 +13,6 @@ require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.7 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.3.7 // indirect
 )
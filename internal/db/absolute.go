@@ -0,0 +1,143 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// watchOrder is the ORDER BY of a show's episodes. Shows with absolute
+// numbering play by absolute number, with the episodes that have none
+// after them, other shows by season and episode, which is by date for
+// daily shows.
+const watchOrder = `CASE WHEN s.numbering = 'absolute' THEN COALESCE(e.absolute, 1000000000) ELSE 0 END, e.season, e.episode`
+
+// SetNumbering switches a show between SeasonNumbering and AbsoluteNumbering.
+func (db *DB) SetNumbering(showID int64, numbering string) error {
+	if numbering != model.SeasonNumbering && numbering != model.AbsoluteNumbering {
+		return fmt.Errorf("unknown numbering %q (use %s or %s)", numbering, model.SeasonNumbering, model.AbsoluteNumbering)
+	}
+	if _, err := db.Conn.Exec(`UPDATE shows SET numbering = ? WHERE id = ?`, numbering, showID); err != nil {
+		return fmt.Errorf("failed to set numbering of show %d: %w", showID, err)
+	}
+	return nil
+}
+
+// absoluteSeasons returns the season and episode of every regular episode
+// in the TMDB catalog of a show, in order, so absolute number n is the
+// n-1th. It is empty until the catalog was fetched.
+func absoluteSeasons(q queryer, showID int64) ([][2]int, error) {
+	rows, err := q.Query(`
+		SELECT c.season, c.episode
+		FROM catalog_episodes c
+		JOIN shows s ON s.tmdb_id = c.tmdb_show_id
+		WHERE s.id = ? AND c.season > 0
+		ORDER BY c.season, c.episode
+	`, showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query catalog: %w", err)
+	}
+	defer rows.Close()
+
+	var order [][2]int
+	for rows.Next() {
+		var se [2]int
+		if err := rows.Scan(&se[0], &se[1]); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog episode: %w", err)
+		}
+		order = append(order, se)
+	}
+	return order, rows.Err()
+}
+
+// MapAbsoluteEpisodes moves the episodes of a show that only have an
+// absolute number to the season and episode the catalog puts them at,
+// keeping their progress and history. It returns how many moved, and the
+// ones left alone because another file already is that episode.
+func (db *DB) MapAbsoluteEpisodes(showID int64) (int, []string, error) {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	n, conflicts, err := mapAbsoluteEpisodes(tx, showID)
+	if err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+	return n, conflicts, tx.Commit()
+}
+
+func mapAbsoluteEpisodes(tx *sql.Tx, showID int64) (int, []string, error) {
+	order, err := absoluteSeasons(tx, showID)
+	if err != nil || len(order) == 0 {
+		return 0, nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, season, episode, absolute FROM episodes
+		WHERE show_id = ? AND absolute > 0
+	`, showID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to query episodes: %w", err)
+	}
+	type move struct {
+		id                  string
+		season, episode     int
+		absolute            int
+		toSeason, toEpisode int
+	}
+	var moves []move
+	for rows.Next() {
+		var m move
+		if err := rows.Scan(&m.id, &m.season, &m.episode, &m.absolute); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("failed to scan episode: %w", err)
+		}
+		if m.absolute > len(order) {
+			continue // newer than the catalog
+		}
+		m.toSeason, m.toEpisode = order[m.absolute-1][0], order[m.absolute-1][1]
+		if m.toSeason != m.season || m.toEpisode != m.episode {
+			moves = append(moves, m)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	var conflicts []string
+	moved := 0
+	for _, m := range moves {
+		newID := episodeID(showID, m.toSeason, m.toEpisode)
+		res, err := tx.Exec(`
+			INSERT OR IGNORE INTO episodes (id, show_id, season, episode, episode_end, absolute, file_path,
+				name, air_date, runtime, file_size, file_hash, missing_since, offline)
+			SELECT ?, show_id, ?, ?, NULL, absolute, file_path,
+				name, air_date, runtime, file_size, file_hash, missing_since, offline
+			FROM episodes WHERE id = ?
+		`, newID, m.toSeason, m.toEpisode, m.id)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to move episode %s: %w", m.id, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			// keep it where it is rather than lose it
+			conflicts = append(conflicts, fmt.Sprintf("absolute episode %d is S%02dE%02d, which another file already is", m.absolute, m.toSeason, m.toEpisode))
+			continue
+		}
+		if err := repointEpisode(tx, m.id, newID); err != nil {
+			return 0, nil, err
+		}
+		_, err = tx.Exec(`
+			UPDATE progress SET last_watched_season = ?, last_watched_episode = ?
+			WHERE show_id = ? AND last_watched_season = ? AND last_watched_episode = ?
+		`, m.toSeason, m.toEpisode, showID, m.season, m.episode)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to move progress of episode %s: %w", m.id, err)
+		}
+		moved++
+	}
+	return moved, conflicts, nil
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// withCatalog matches the show to a TMDB catalog with the given number of
+// episodes in each season, from season 1.
+func withCatalog(t *testing.T, db *DB, showID int64, seasons ...int) {
+	t.Helper()
+	var eps []model.Episode
+	for i, n := range seasons {
+		for e := 1; e <= n; e++ {
+			eps = append(eps, model.Episode{Season: i + 1, Episode: e})
+		}
+	}
+	if err := db.SetShowTMDBID(showID, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveCatalog(42, eps); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMapAbsoluteEpisodes(t *testing.T) {
+	db := newTestDB(t)
+	sync(t, db, "/tv",
+		model.Episode{Title: "Show", Season: 1, Episode: 5, Absolute: 5, Path: "/tv/Show/Show - 05.mkv"},
+		model.Episode{Title: "Show", Season: 1, Episode: 6, Absolute: 6, Path: "/tv/Show/Show - 06.mkv"},
+	)
+	show, err := db.FindShow("Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.Numbering != model.AbsoluteNumbering {
+		t.Errorf("numbering = %q, want absolute", show.Numbering)
+	}
+	if err := db.SaveProgress(show.ID, 1, 5, 0); err != nil {
+		t.Fatal(err)
+	}
+	withCatalog(t, db, show.ID, 4, 3)
+
+	n, conflicts, err := db.MapAbsoluteEpisodes(show.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(conflicts) != 0 {
+		t.Fatalf("moved %d with conflicts %v, want 2 and none", n, conflicts)
+	}
+	for _, se := range [][2]int{{2, 1}, {2, 2}} {
+		if _, err := db.GetEpisode(show.ID, se[0], se[1]); err != nil {
+			t.Errorf("S%02dE%02d: %v", se[0], se[1], err)
+		}
+	}
+	latest, err := db.FindLatestWatchedEpisode("Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Season != 2 || latest.Episode != 1 {
+		t.Errorf("progress is on %s, want S02E01", latest.Code())
+	}
+}
+
+func TestMapAbsoluteEpisodesKeepsCollisions(t *testing.T) {
+	db := newTestDB(t)
+	sync(t, db, "/tv",
+		model.Episode{Title: "Show", Season: 1, Episode: 5, Absolute: 5, Path: "/tv/Show/Show - 05.mkv"},
+		model.Episode{Title: "Show", Season: 2, Episode: 1, Path: "/tv/Show/Show.S02E01.mkv"},
+	)
+	show, err := db.FindShow("Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withCatalog(t, db, show.ID, 4, 3)
+
+	n, conflicts, err := db.MapAbsoluteEpisodes(show.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || len(conflicts) != 1 {
+		t.Fatalf("moved %d with conflicts %v, want 0 and one", n, conflicts)
+	}
+	ep, err := db.GetEpisode(show.ID, 1, 5)
+	if err != nil {
+		t.Fatalf("the absolute numbered episode was lost: %v", err)
+	}
+	if ep.Path != "/tv/Show/Show - 05.mkv" {
+		t.Errorf("S01E05 is %s", ep.Path)
+	}
+}
+
+func TestSyncAbsoluteEpisodeSlotTaken(t *testing.T) {
+	db := newTestDB(t)
+	regular := model.Episode{Title: "Show", Season: 2, Episode: 1, Path: "/tv/Show/Season 2/Show.S02E01.mkv"}
+	sync(t, db, "/tv", regular)
+	show, err := db.FindShow("Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withCatalog(t, db, show.ID, 4, 3)
+
+	// absolute 5 is S02E01, which the other file already is
+	abs := model.Episode{Title: "Show", Season: 1, Episode: 5, Absolute: 5, Path: "/tv/Show/Show - 05.mkv"}
+	changes := sync(t, db, "/tv", regular, abs)
+	if len(changes.Conflicts) != 1 {
+		t.Errorf("conflicts are %v, want one", changes.Conflicts)
+	}
+	if len(changes.Added) != 1 || len(changes.Removed) != 0 {
+		t.Errorf("expected the new file to be added, got %+v", changes)
+	}
+
+	ep, err := db.GetEpisode(show.ID, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Path != regular.Path {
+		t.Errorf("S02E01 is %s, want %s", ep.Path, regular.Path)
+	}
+	// kept at its absolute number
+	ep, err = db.GetEpisode(show.ID, 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Path != abs.Path {
+		t.Errorf("S01E05 is %s, want %s", ep.Path, abs.Path)
+	}
+}
+
+func TestSyncAbsoluteEpisodeSameSlotTaken(t *testing.T) {
+	db := newTestDB(t)
+	first := model.Episode{Title: "Show", Season: 1, Episode: 1, Path: "/tv/Show/Season 1/Show.S01E01.mkv"}
+	sync(t, db, "/tv", first)
+	show, err := db.FindShow("Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withCatalog(t, db, show.ID, 4, 3)
+
+	// both new, absolute 2 is S01E02 where it would be kept too, and the
+	// file numbered by season wins whatever the order
+	abs := model.Episode{Title: "Show", Season: 1, Episode: 2, Absolute: 2, Path: "/tv/Show/Show - 02.mkv"}
+	regular := model.Episode{Title: "Show", Season: 1, Episode: 2, Path: "/tv/Show/Season 1/Show.S01E02.mkv"}
+	changes := sync(t, db, "/tv", first, abs, regular)
+	if len(changes.Conflicts) != 1 || len(changes.Added) != 1 || changes.Added[0].Path != regular.Path {
+		t.Errorf("expected a conflict and only %s added, got %+v", regular.Path, changes)
+	}
+	ep, err := db.GetEpisode(show.ID, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Path != regular.Path {
+		t.Errorf("S01E02 is %s, want %s", ep.Path, regular.Path)
+	}
+}
@@ -0,0 +1,395 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// Move is an episode whose file was found at a new path.
+type Move struct {
+	From    string        `json:"from"`
+	Episode model.Episode `json:"episode"` // at its new path
+}
+
+// ScanChanges is how a scan changed the library.
+type ScanChanges struct {
+	Added     []model.Episode `json:"added"`
+	Removed   []model.Episode `json:"removed"`
+	Moved     []Move          `json:"moved"`
+	Conflicts []string        `json:"conflicts"` // absolute numbered files whose catalog slot is taken
+}
+
+// FileState is what a scan remembers of a video file, to only parse it
+// again once it changed.
+type FileState struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"` // unix nanoseconds
+}
+
+// ScannedTree is what a scan of a library root saw on disk.
+type ScannedTree struct {
+	Root     string
+	Files    []FileState     // every video file under Root
+	Episodes []model.Episode // parsed from the files that are new or changed
+	Skipped  []string        // folders that couldn't be read, nothing below them is pruned
+}
+
+// covers reports whether path is below a folder the scan couldn't read.
+func (t *ScannedTree) covers(path string) bool {
+	for _, dir := range t.Skipped {
+		if inRoot(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// inRoot reports whether path is root or below it.
+func inRoot(path, root string) bool {
+	root = filepath.Clean(root)
+	path = filepath.Clean(path)
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// storedEpisode is an episode with the file details kept for move detection.
+type storedEpisode struct {
+	model.Episode
+	size int64
+	hash string
+}
+
+// presentEpisodes returns the episodes under root that aren't missing.
+func presentEpisodes(q queryer, root string) ([]storedEpisode, error) {
+	rows, err := q.Query(`
+		SELECT ` + episodeColumns + `, COALESCE(e.file_size, 0), COALESCE(e.file_hash, '')
+		FROM episodes e
+		JOIN shows s ON s.id = e.show_id
+		WHERE e.missing_since IS NULL
+		ORDER BY e.season, e.episode
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query episodes: %w", err)
+	}
+	defer rows.Close()
+
+	var eps []storedEpisode
+	for rows.Next() {
+		var ep storedEpisode
+		if err := rows.Scan(append(episodeFields(&ep.Episode), &ep.size, &ep.hash)...); err != nil {
+			return nil, fmt.Errorf("failed to scan episode: %w", err)
+		}
+		if inRoot(ep.Path, root) {
+			eps = append(eps, ep)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return eps, nil
+}
+
+// FileStates returns the state of the video files under root as of the
+// last scan, by path.
+func (db *DB) FileStates(root string) (map[string]FileState, error) {
+	return fileStates(db.Conn, root)
+}
+
+func fileStates(q queryer, root string) (map[string]FileState, error) {
+	rows, err := q.Query(`SELECT path, size, mtime FROM files`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file states: %w", err)
+	}
+	defer rows.Close()
+
+	files := make(map[string]FileState)
+	for rows.Next() {
+		var f FileState
+		if err := rows.Scan(&f.Path, &f.Size, &f.ModTime); err != nil {
+			return nil, fmt.Errorf("failed to scan file state: %w", err)
+		}
+		if inRoot(f.Path, root) {
+			files[f.Path] = f
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return files, nil
+}
+
+// SyncEpisodes saves what a scan found and reconciles the library with the
+// disk, in one transaction with the file states so an interrupted scan is
+// simply done again. Episodes whose file is gone are marked missing, and a
+// new file with the size and partial hash of a vanished one is taken as a
+// move, keeping the progress and history of the episode.
+func (db *DB) SyncEpisodes(tree *ScannedTree) (*ScanChanges, error) {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	// the root was read, whatever it holds is back online
+	if _, err := setOffline(tx, tree.Root, false); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	changes, err := syncEpisodes(tx, tree)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := saveFileStates(tx, tree); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return changes, tx.Commit()
+}
+
+func saveFileStates(tx *sql.Tx, tree *ScannedTree) error {
+	old, err := fileStates(tx, tree.Root)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO files (path, size, mtime) VALUES (?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET size = excluded.size, mtime = excluded.mtime
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, f := range tree.Files {
+		delete(old, f.Path)
+		if _, err := stmt.Exec(f.Path, f.Size, f.ModTime); err != nil {
+			return fmt.Errorf("failed to save state of %s: %w", f.Path, err)
+		}
+	}
+
+	// whatever is left wasn't seen anymore
+	for path := range old {
+		if tree.covers(path) {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM files WHERE path = ?`, path); err != nil {
+			return fmt.Errorf("failed to forget %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func syncEpisodes(tx *sql.Tx, tree *ScannedTree) (*ScanChanges, error) {
+	changes := &ScanChanges{Added: []model.Episode{}, Removed: []model.Episode{}, Moved: []Move{}, Conflicts: []string{}}
+	eps := tree.Episodes
+
+	known, err := presentEpisodes(tx, tree.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	onDisk := make(map[string]bool, len(tree.Files))
+	for _, f := range tree.Files {
+		onDisk[f.Path] = true
+	}
+
+	knownPaths := make(map[string]bool, len(known))
+	var vanished []storedEpisode
+	for _, ep := range known {
+		knownPaths[ep.Path] = true
+		if !onDisk[ep.Path] && !tree.covers(ep.Path) {
+			vanished = append(vanished, ep)
+		}
+	}
+
+	type fileKey struct {
+		size int64
+		hash string
+	}
+	byFile := make(map[fileKey]int)
+	for i, ep := range vanished {
+		key := fileKey{ep.size, ep.hash}
+		if _, ok := byFile[key]; !ok && ep.hash != "" {
+			// the first episode of a multi-episode file stands for the file
+			byFile[key] = i
+		}
+	}
+
+	moved := make(map[int]int) // index in eps -> index in vanished
+	used := make(map[int]bool)
+	var added []int
+	for i, ep := range eps {
+		if knownPaths[ep.Path] {
+			continue
+		}
+		if v, ok := byFile[fileKey{ep.Size, ep.Hash}]; ok && ep.Hash != "" && !used[v] {
+			moved[i] = v
+			used[v] = true
+			continue
+		}
+		added = append(added, i)
+	}
+
+	conflicts, err := saveEpisodes(tx, eps)
+	if err != nil {
+		return nil, err
+	}
+	changes.Conflicts = append(changes.Conflicts, conflicts...)
+
+	// every episode a saved file holds, by id
+	savedIDs := make(map[string]int, len(eps))
+	for i, ep := range eps {
+		if ep.Id == "" {
+			// left out for a conflict
+			delete(moved, i)
+			continue
+		}
+		for n := ep.Episode; n <= max(ep.Episode, ep.EpisodeEnd); n++ {
+			savedIDs[episodeID(ep.ShowID, ep.Season, n)] = i
+		}
+	}
+
+	vanishedByPath := make(map[string][]int)
+	for v, ep := range vanished {
+		vanishedByPath[ep.Path] = append(vanishedByPath[ep.Path], v)
+	}
+
+	reported := make(map[string]bool) // old paths with a Move
+	for i := range eps {
+		v, ok := moved[i]
+		if !ok {
+			continue
+		}
+		old := vanished[v]
+		// the rest of a multi-episode file moves along with its first episode
+		for _, w := range vanishedByPath[old.Path] {
+			n := eps[i].Episode + vanished[w].Episode.Episode - old.Episode.Episode
+			if w != v && (used[w] || n > max(eps[i].Episode, eps[i].EpisodeEnd)) {
+				continue
+			}
+			used[w] = true
+			if newID := episodeID(eps[i].ShowID, eps[i].Season, n); vanished[w].Id != newID {
+				if err := repointEpisode(tx, vanished[w].Id, newID); err != nil {
+					return nil, err
+				}
+			}
+		}
+		changes.Moved = append(changes.Moved, Move{From: old.Path, Episode: eps[i]})
+		reported[old.Path] = true
+	}
+
+	if err := reparsedEpisodes(tx, known, eps, savedIDs); err != nil {
+		return nil, err
+	}
+
+	for v, old := range vanished {
+		if used[v] {
+			continue
+		}
+		res, err := tx.Exec(`
+			UPDATE episodes SET missing_since = CURRENT_TIMESTAMP WHERE id = ? AND file_path = ?
+		`, old.Id, old.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark %s missing: %w", old.Path, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changes.Removed = append(changes.Removed, old.Episode)
+			continue
+		}
+		// the same episode was saved from another file, a rename
+		if i, ok := savedIDs[old.Id]; ok && !reported[old.Path] {
+			reported[old.Path] = true
+			changes.Moved = append(changes.Moved, Move{From: old.Path, Episode: eps[i]})
+			for j, a := range added {
+				if a == i {
+					added = append(added[:j], added[j+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	for _, i := range added {
+		if eps[i].Id == "" {
+			continue
+		}
+		changes.Added = append(changes.Added, eps[i])
+	}
+	return changes, nil
+}
+
+// reparsedEpisodes hands the progress and history of the episodes a file
+// was parsed as before, by an older parser, to what it is parsed as now,
+// and drops them.
+func reparsedEpisodes(tx *sql.Tx, known []storedEpisode, eps []model.Episode, savedIDs map[string]int) error {
+	parsed := make(map[string]int, len(eps))
+	for i, ep := range eps {
+		if ep.Id != "" {
+			parsed[ep.Path] = i
+		}
+	}
+	first := make(map[string]int) // lowest episode a file was, known is in order
+	for _, old := range known {
+		if _, ok := first[old.Path]; !ok {
+			first[old.Path] = old.Episode.Episode
+		}
+	}
+
+	for _, old := range known {
+		i, ok := parsed[old.Path]
+		if !ok {
+			continue
+		}
+		if _, ok := savedIDs[old.Id]; ok {
+			// still that episode, or another file took it over
+			continue
+		}
+		ep := eps[i]
+		n := ep.Episode + old.Episode.Episode - first[old.Path]
+		if n > max(ep.Episode, ep.EpisodeEnd) {
+			n = ep.Episode
+		}
+		if err := repointEpisode(tx, old.Id, episodeID(ep.ShowID, ep.Season, n)); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			UPDATE OR IGNORE progress SET show_id = ?, last_watched_season = ?, last_watched_episode = ?
+			WHERE show_id = ? AND last_watched_season = ? AND last_watched_episode = ?
+		`, ep.ShowID, ep.Season, n, old.ShowID, old.Season, old.Episode.Episode)
+		if err != nil {
+			return fmt.Errorf("failed to move progress of %s: %w", old.Path, err)
+		}
+	}
+	return nil
+}
+
+// repointEpisode moves the progress and history of an episode to another
+// id and drops the old row.
+func repointEpisode(tx *sql.Tx, oldID, newID string) error {
+	for _, stmt := range []string{
+		`UPDATE OR IGNORE episode_progress SET episode_id = ? WHERE episode_id = ?`,
+		`UPDATE watch_history SET episode_id = ? WHERE episode_id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, newID, oldID); err != nil {
+			return fmt.Errorf("failed to move progress of episode %s: %w", oldID, err)
+		}
+	}
+	for _, stmt := range []string{
+		`DELETE FROM episode_progress WHERE episode_id = ?`,
+		`DELETE FROM episodes WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, oldID); err != nil {
+			return fmt.Errorf("failed to remove episode %s: %w", oldID, err)
+		}
+	}
+	return nil
+}
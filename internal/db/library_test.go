@@ -0,0 +1,312 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := InitDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// sync saves what a scan of root found, with every file listed on disk.
+func sync(t *testing.T, db *DB, root string, eps ...model.Episode) *ScanChanges {
+	t.Helper()
+	tree := &ScannedTree{Root: root, Episodes: eps}
+	for _, ep := range eps {
+		tree.Files = append(tree.Files, FileState{Path: ep.Path, Size: ep.Size})
+	}
+	changes, err := db.SyncEpisodes(tree)
+	if err != nil {
+		t.Fatalf("SyncEpisodes: %v", err)
+	}
+	return changes
+}
+
+func TestSyncMarksVanishedFilesMissing(t *testing.T) {
+	db := newTestDB(t)
+	first := model.Episode{Title: "Lost", Season: 1, Episode: 1, Path: "/tv/Lost/Lost.S01E01.mkv"}
+	second := model.Episode{Title: "Lost", Season: 1, Episode: 2, Path: "/tv/Lost/Lost.S01E02.mkv"}
+	if changes := sync(t, db, "/tv", first, second); len(changes.Added) != 2 {
+		t.Fatalf("expected two added episodes, got %+v", changes)
+	}
+
+	changes := sync(t, db, "/tv", first)
+	if len(changes.Removed) != 1 || changes.Removed[0].Path != second.Path {
+		t.Fatalf("expected %s to be removed, got %+v", second.Path, changes)
+	}
+	shows, err := db.ListShows("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shows) != 1 || shows[0].Episodes != 1 {
+		t.Errorf("the missing episode is still listed: %+v", shows)
+	}
+	files, err := db.FileStates("/tv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files[second.Path]; ok || len(files) != 1 {
+		t.Errorf("file states still hold the vanished file: %+v", files)
+	}
+
+	// gone is gone, the next scan doesn't report it again
+	if changes := sync(t, db, "/tv", first); len(changes.Removed) != 0 {
+		t.Errorf("reported %+v again", changes.Removed)
+	}
+}
+
+func TestSyncKeepsUnchangedFiles(t *testing.T) {
+	db := newTestDB(t)
+	ep := model.Episode{Title: "Lost", Season: 1, Episode: 1, Path: "/tv/Lost/Lost.S01E01.mkv", Size: 100}
+	sync(t, db, "/tv", ep)
+
+	// still there, but not parsed again
+	changes, err := db.SyncEpisodes(&ScannedTree{Root: "/tv", Files: []FileState{{Path: ep.Path, Size: 100}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Removed) != 0 || len(changes.Added) != 0 {
+		t.Errorf("unchanged file changed the library: %+v", changes)
+	}
+}
+
+func TestSyncKeepsSkippedFolders(t *testing.T) {
+	db := newTestDB(t)
+	lost := model.Episode{Title: "Lost", Season: 1, Episode: 1, Path: "/tv/Lost/Lost.S01E01.mkv"}
+	house := model.Episode{Title: "House", Season: 1, Episode: 1, Path: "/tv/House/House.S01E01.mkv"}
+	sync(t, db, "/tv", lost, house)
+
+	// House couldn't be read this time
+	changes, err := db.SyncEpisodes(&ScannedTree{
+		Root:    "/tv",
+		Files:   []FileState{{Path: lost.Path}},
+		Skipped: []string{"/tv/House"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Removed) != 0 {
+		t.Errorf("pruned %+v from a folder that wasn't read", changes.Removed)
+	}
+	files, err := db.FileStates("/tv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files[house.Path]; !ok {
+		t.Error("forgot the state of a file in a folder that wasn't read")
+	}
+}
+
+func TestSyncDetectsMovedFile(t *testing.T) {
+	db := newTestDB(t)
+	ep := model.Episode{Title: "Lost", Season: 1, Episode: 1, Path: "/tv/Lost/Lost.S01E01.mkv", Size: 100, Hash: "abc"}
+	sync(t, db, "/tv", ep)
+	show, err := db.FindShow("Lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := db.GetEpisode(show.ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordWatch(old.Id, 90, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	ep.Path = "/tv/Lost/Season 1/Lost.S01E01.mkv"
+	changes := sync(t, db, "/tv", ep)
+	if len(changes.Moved) != 1 || len(changes.Added) != 0 || len(changes.Removed) != 0 {
+		t.Fatalf("expected one move, got %+v", changes)
+	}
+	if changes.Moved[0].From != "/tv/Lost/Lost.S01E01.mkv" {
+		t.Errorf("moved from %s", changes.Moved[0].From)
+	}
+	moved, err := db.GetEpisode(show.ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Path != ep.Path {
+		t.Errorf("episode plays %s, want %s", moved.Path, ep.Path)
+	}
+	if watched, _ := db.IsWatched(moved.Id); !watched {
+		t.Error("history was lost in the move")
+	}
+}
+
+func TestSyncMovesFileToAnotherShow(t *testing.T) {
+	db := newTestDB(t)
+	ep := model.Episode{Title: "Lsot", Season: 1, Episode: 1, Path: "/tv/Lsot/Lost.S01E01.mkv", Size: 100, Hash: "abc"}
+	sync(t, db, "/tv", ep)
+	typo, err := db.FindShow("Lsot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := db.GetEpisode(typo.ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveEpisodeProgress(old.Id, 300, 2400); err != nil {
+		t.Fatal(err)
+	}
+
+	// the folder got its name fixed
+	ep.Title, ep.Path = "Lost", "/tv/Lost/Lost.S01E01.mkv"
+	if changes := sync(t, db, "/tv", ep); len(changes.Moved) != 1 {
+		t.Fatalf("expected one move, got %+v", changes)
+	}
+	shows, err := db.ListShows("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shows) != 1 || shows[0].Title != "Lost" {
+		t.Fatalf("expected only the fixed show, got %+v", shows)
+	}
+	moved, err := db.GetEpisode(shows[0].ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resume, err := db.GetEpisodeProgress(moved.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resume.Position != 300 {
+		t.Errorf("resume point is %d after the move, want 300", resume.Position)
+	}
+}
+
+func TestSyncRenamedFile(t *testing.T) {
+	db := newTestDB(t)
+	// no hash to recognize it by, but it's still the same episode
+	sync(t, db, "/tv", model.Episode{Title: "Lost", Season: 1, Episode: 1, Path: "/tv/Lost/lost.s01e01.mkv"})
+	changes := sync(t, db, "/tv", model.Episode{Title: "Lost", Season: 1, Episode: 1, Path: "/tv/Lost/Lost.S01E01.mkv"})
+	if len(changes.Moved) != 1 || len(changes.Added) != 0 || len(changes.Removed) != 0 {
+		t.Fatalf("expected one move, got %+v", changes)
+	}
+}
+
+func TestSyncDailyEpisode(t *testing.T) {
+	db := newTestDB(t)
+	sync(t, db, "/tv",
+		model.Episode{Title: "The Daily Show", Season: 2025, Episode: 314, Date: "2025-03-14", Path: "/tv/The Daily Show/The.Daily.Show.2025.03.14.mkv"},
+		model.Episode{Title: "The Daily Show", Season: 2025, Episode: 317, Date: "2025-03-17", Path: "/tv/The Daily Show/The.Daily.Show.2025.03.17.mkv"},
+	)
+	show, err := db.FindShow("The Daily Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ep, err := db.GetEpisodeByDate(show.ID, "2025-03-17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Date != "2025-03-17" || ep.Code() != "2025-03-17" {
+		t.Errorf("got %+v", ep)
+	}
+	if _, err := db.GetEpisodeByDate(show.ID, "2025-03-15"); err == nil {
+		t.Error("found an episode that didn't air")
+	}
+
+	// they play in the order they aired
+	next, err := db.GetNextEpisodes(show.ID, 2025, 314, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next) != 1 || next[0].Date != "2025-03-17" {
+		t.Errorf("next episodes are %+v", next)
+	}
+}
+
+func TestSyncMovesMultiEpisodeFileToAnotherShow(t *testing.T) {
+	db := newTestDB(t)
+	ep := model.Episode{Title: "Hous", Season: 1, Episode: 1, EpisodeEnd: 2,
+		Path: "/tv/Hous/House.S01E01E02.mkv", Size: 100, Hash: "abc"}
+	sync(t, db, "/tv", ep)
+
+	old, err := db.FindShow("Hous")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := db.GetEpisode(old.ID, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordWatch(second.Id, 90, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	// the folder got its name fixed, which makes it another show
+	ep.Title, ep.Path = "House", "/tv/House/House.S01E01E02.mkv"
+	changes := sync(t, db, "/tv", ep)
+	if len(changes.Removed) != 0 || len(changes.Added) != 0 || len(changes.Moved) != 1 {
+		t.Fatalf("expected one move, got %+v", changes)
+	}
+
+	show, err := db.FindShow("House")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.ID == old.ID {
+		t.Fatal("expected the fixed title to be a new show")
+	}
+	moved, err := db.GetEpisode(show.ID, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watched, err := db.IsWatched(moved.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !watched {
+		t.Error("history of the second episode was not moved along with the file")
+	}
+}
+
+func TestSyncReparsedFileKeepsHistory(t *testing.T) {
+	db := newTestDB(t)
+	path := "/tv/Show/Season 1/05 - Pilot.mkv"
+	// what an older parser made of it
+	sync(t, db, "/tv", model.Episode{Title: "05 - Pilot", Season: 0, Episode: 0, Path: path})
+	wrong, err := db.FindShow("05 - Pilot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := db.GetEpisode(wrong.ID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordWatch(old.Id, 90, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveProgress(wrong.ID, 0, 0, 90); err != nil {
+		t.Fatal(err)
+	}
+
+	sync(t, db, "/tv", model.Episode{Title: "Show", Season: 1, Episode: 5, Path: path})
+
+	shows, err := db.ListShows("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shows) != 1 || shows[0].Title != "Show" {
+		t.Fatalf("expected only the right show, got %+v", shows)
+	}
+	if shows[0].LastSeason != 1 || shows[0].LastEpisode != 5 {
+		t.Errorf("progress is on S%02dE%02d, want S01E05", shows[0].LastSeason, shows[0].LastEpisode)
+	}
+	ep, err := db.GetEpisode(shows[0].ID, 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watched, _ := db.IsWatched(ep.Id); !watched {
+		t.Error("history stayed with the wrongly parsed episode")
+	}
+}
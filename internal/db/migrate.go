@@ -0,0 +1,450 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+)
+
+// migration upgrades the schema by one version. Databases created before
+// versioning have no schema_version table, so the early migrations must
+// cope with tables and columns that already exist.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations must stay in order, and released ones must never change.
+var migrations = []migration{
+	{1, "initial schema", func(tx *sql.Tx) error {
+		return execAll(tx, `
+			CREATE TABLE IF NOT EXISTS episodes (
+				id TEXT PRIMARY KEY,
+				show_title TEXT,
+				season INTEGER,
+				episode INTEGER,
+				file_path TEXT
+			)`, `
+			CREATE TABLE IF NOT EXISTS progress (
+				show_title TEXT PRIMARY KEY,
+				last_watched_season INTEGER,
+				last_watched_episode INTEGER,
+				progress INTEGER,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`, `
+			CREATE TABLE IF NOT EXISTS settings (
+				key TEXT PRIMARY KEY,
+				value TEXT,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`, `
+			CREATE TABLE IF NOT EXISTS folder_hashes (
+				path TEXT PRIMARY KEY,
+				hash TEXT
+			)`,
+		)
+	}},
+	{2, "episode progress and watch history", func(tx *sql.Tx) error {
+		return execAll(tx, `
+			CREATE TABLE IF NOT EXISTS episode_progress (
+				episode_id TEXT PRIMARY KEY,
+				position INTEGER,
+				duration INTEGER,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`, `
+			CREATE TABLE IF NOT EXISTS watch_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				episode_id TEXT,
+				position INTEGER,
+				duration INTEGER,
+				watched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS watch_history_episode ON watch_history(episode_id)`,
+		)
+	}},
+	{3, "tmdb metadata, cache and catalog", func(tx *sql.Tx) error {
+		// metadata from TMDB, filled in by enrichment
+		for _, col := range [][2]string{
+			{"tmdb_show_id", "INTEGER"},
+			{"name", "TEXT"},
+			{"air_date", "TEXT"},
+			{"runtime", "INTEGER"},
+		} {
+			if err := ensureColumn(tx, "episodes", col[0], col[1]); err != nil {
+				return err
+			}
+		}
+		return execAll(tx, `
+			CREATE TABLE IF NOT EXISTS tmdb_cache (
+				key TEXT PRIMARY KEY,
+				body BLOB,
+				fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`, `
+			CREATE TABLE IF NOT EXISTS catalog_episodes (
+				tmdb_show_id INTEGER,
+				season INTEGER,
+				episode INTEGER,
+				name TEXT,
+				air_date TEXT,
+				runtime INTEGER,
+				PRIMARY KEY (tmdb_show_id, season, episode)
+			)`,
+		)
+	}},
+	{4, "normalized shows", migrateShows},
+	{5, "collision-free episode ids", migrateEpisodeIDs},
+	{6, "file identity and missing episodes", func(tx *sql.Tx) error {
+		// size and partial hash recognize a file after a move
+		for _, col := range [][2]string{
+			{"file_size", "INTEGER"},
+			{"file_hash", "TEXT"},
+			{"missing_since", "DATETIME"},
+		} {
+			if err := ensureColumn(tx, "episodes", col[0], col[1]); err != nil {
+				return err
+			}
+		}
+		return execAll(tx, `CREATE INDEX IF NOT EXISTS episodes_file ON episodes(file_size, file_hash)`)
+	}},
+	{7, "per-file scan state", func(tx *sql.Tx) error {
+		// folder hashes skipped whole subtrees, files are tracked one by one now
+		return execAll(tx, `
+			CREATE TABLE files (
+				path TEXT PRIMARY KEY,
+				size INTEGER,
+				mtime INTEGER
+			)`,
+			`DROP TABLE IF EXISTS folder_hashes`,
+		)
+	}},
+	{8, "library roots", func(tx *sql.Tx) error {
+		// episodes on a root that is offline stay in the library, unplayable
+		if err := ensureColumn(tx, "episodes", "offline", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+		return execAll(tx, `
+			CREATE TABLE library_roots (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				path TEXT NOT NULL UNIQUE,
+				enabled INTEGER NOT NULL DEFAULT 1,
+				ignore TEXT NOT NULL DEFAULT '',
+				offline_when_unmounted INTEGER NOT NULL DEFAULT 0
+			)`, `
+			INSERT INTO library_roots (path)
+			SELECT value FROM settings WHERE key = 'scan_path' AND value != ''`,
+		)
+	}},
+	{9, "multi-episode files", func(tx *sql.Tx) error {
+		// every episode of a file gets a row, all with the last one's number
+		return ensureColumn(tx, "episodes", "episode_end", "INTEGER")
+	}},
+	{10, "absolute numbering", func(tx *sql.Tx) error {
+		if err := ensureColumn(tx, "episodes", "absolute", "INTEGER"); err != nil {
+			return err
+		}
+		// NULL until a scan or the user picks one
+		return ensureColumn(tx, "shows", "numbering", "TEXT")
+	}},
+	{11, "dated episodes", func(tx *sql.Tx) error {
+		if err := ensureColumn(tx, "episodes", "date", "TEXT"); err != nil {
+			return err
+		}
+		return execAll(tx, `CREATE INDEX IF NOT EXISTS episodes_date ON episodes(show_id, date)`)
+	}},
+	{12, "specials placement", func(tx *sql.Tx) error {
+		// the regular episode a special aired before, filled in with the catalog
+		for _, col := range []string{"airs_before_season", "airs_before_episode", "airs_before_absolute"} {
+			if err := ensureColumn(tx, "catalog_episodes", col, "INTEGER"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{13, "re-parse with the new parser", func(tx *sql.Tx) error {
+		// the next scan parses every file again, the episodes parsed wrong
+		// before hand their history to the right ones
+		return execAll(tx, `DELETE FROM files`)
+	}},
+}
+
+// migrateShows moves show titles out of episodes and progress into a shows
+// table they refer to by id. The titles stored so far were lowercased, so
+// the folder hashes are dropped to make the next scan restore their casing.
+func migrateShows(tx *sql.Tx) error {
+	err := execAll(tx, `
+		CREATE TABLE shows (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			title_key TEXT NOT NULL,
+			sort_title TEXT,
+			year INTEGER NOT NULL DEFAULT 0,
+			tmdb_id INTEGER,
+			folder_path TEXT,
+			UNIQUE (title_key, year)
+		)`, `
+		CREATE TABLE episodes_new (
+			id TEXT PRIMARY KEY,
+			show_id INTEGER NOT NULL REFERENCES shows(id),
+			season INTEGER,
+			episode INTEGER,
+			file_path TEXT,
+			name TEXT,
+			air_date TEXT,
+			runtime INTEGER
+		)`, `
+		CREATE TABLE progress_new (
+			show_id INTEGER PRIMARY KEY REFERENCES shows(id),
+			last_watched_season INTEGER,
+			last_watched_episode INTEGER,
+			progress INTEGER,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT COALESCE(show_title, ''), MAX(COALESCE(tmdb_show_id, 0)) FROM episodes GROUP BY show_title
+		UNION
+		SELECT COALESCE(show_title, ''), 0 FROM progress
+	`)
+	if err != nil {
+		return err
+	}
+	type oldShow struct {
+		title  string
+		tmdbID int
+	}
+	var old []oldShow
+	for rows.Next() {
+		var s oldShow
+		if err := rows.Scan(&s.title, &s.tmdbID); err != nil {
+			rows.Close()
+			return err
+		}
+		old = append(old, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range old {
+		id, err := saveShow(tx, s.title, 0, "")
+		if err != nil {
+			return err
+		}
+		if s.tmdbID > 0 {
+			if _, err := tx.Exec(`UPDATE shows SET tmdb_id = ? WHERE id = ?`, s.tmdbID, id); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`
+			INSERT OR IGNORE INTO episodes_new (id, show_id, season, episode, file_path, name, air_date, runtime)
+			SELECT id, ?, season, episode, file_path, name, air_date, runtime
+			FROM episodes WHERE COALESCE(show_title, '') = ?
+		`, id, s.title)
+		if err != nil {
+			return err
+		}
+
+		// titles that only differed in punctuation keep the latest progress
+		_, err = tx.Exec(`
+			INSERT INTO progress_new (show_id, last_watched_season, last_watched_episode, progress, updated_at)
+			SELECT ?, last_watched_season, last_watched_episode, progress, updated_at
+			FROM progress WHERE COALESCE(show_title, '') = ?
+			ON CONFLICT(show_id) DO UPDATE SET
+				last_watched_season = excluded.last_watched_season,
+				last_watched_episode = excluded.last_watched_episode,
+				progress = excluded.progress,
+				updated_at = excluded.updated_at
+			WHERE excluded.updated_at > progress_new.updated_at
+		`, id, s.title)
+		if err != nil {
+			return err
+		}
+	}
+
+	return execAll(tx,
+		`DROP TABLE episodes`,
+		`ALTER TABLE episodes_new RENAME TO episodes`,
+		`DROP TABLE progress`,
+		`ALTER TABLE progress_new RENAME TO progress`,
+		`CREATE INDEX episodes_show ON episodes(show_id, season, episode)`,
+		`DELETE FROM folder_hashes`,
+	)
+}
+
+// migrateEpisodeIDs rewrites every episode id with episodeID. The old ids
+// hashed the season and episode as single bytes, so episode 256 overwrote
+// episode 0. Rows merged that way can't be told apart anymore, dropping the
+// folder hashes makes the next scan add the lost episodes back.
+func migrateEpisodeIDs(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, show_id, season, episode FROM episodes`)
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string)
+	for rows.Next() {
+		var id string
+		var showID int64
+		var season, episode int
+		if err := rows.Scan(&id, &showID, &season, &episode); err != nil {
+			rows.Close()
+			return err
+		}
+		ids[id] = episodeID(showID, season, episode)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for oldID, newID := range ids {
+		// an id already taken means a duplicate of the same episode, whose
+		// row and resume point give way to the existing ones
+		stmts := []string{
+			`UPDATE OR IGNORE episodes SET id = ? WHERE id = ?`,
+			`UPDATE OR IGNORE episode_progress SET episode_id = ? WHERE episode_id = ?`,
+			`UPDATE watch_history SET episode_id = ? WHERE episode_id = ?`,
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt, newID, oldID); err != nil {
+				return err
+			}
+		}
+		if oldID == newID {
+			continue
+		}
+		for _, stmt := range []string{
+			`DELETE FROM episodes WHERE id = ?`,
+			`DELETE FROM episode_progress WHERE episode_id = ?`,
+		} {
+			if _, err := tx.Exec(stmt, oldID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return execAll(tx, `DELETE FROM folder_hashes`)
+}
+
+// SchemaVersion is the schema version this binary writes.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrate brings the database at path up to SchemaVersion. The file is
+// backed up before the first pending migration runs, and all of them run
+// in one transaction so a failure leaves the database untouched.
+func migrate(conn *sql.DB, path string) error {
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	var current int
+	if err := conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	latest := SchemaVersion()
+	if current > latest {
+		return fmt.Errorf("database %s uses schema version %d but this showtracker only knows up to %d, please upgrade showtracker", path, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	if err := backup(conn, path, current); err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// backup copies a database that already holds data next to the original,
+// as path.v<version>.bak.
+func backup(conn *sql.DB, path string, version int) error {
+	var tables int
+	err := conn.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name != 'schema_version'
+	`).Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("failed to inspect database: %w", err)
+	}
+	if tables == 0 || path == ":memory:" {
+		return nil // fresh database, nothing to lose
+	}
+
+	dest := fmt.Sprintf("%s.v%d.bak", path, version)
+	os.Remove(dest) // VACUUM INTO refuses to overwrite
+	if _, err := conn.Exec(`VACUUM INTO ?`, dest); err != nil {
+		return fmt.Errorf("failed to back up database to %s: %w", dest, err)
+	}
+	log.Printf("Backed up database to %s before upgrading it", dest)
+	return nil
+}
+
+func execAll(tx *sql.Tx, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureColumn adds a column to an existing table unless it is already there.
+func ensureColumn(tx *sql.Tx, table, column, decl string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
+}
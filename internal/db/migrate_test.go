@@ -0,0 +1,195 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// oldDB writes a database the way showtracker did before schema versions.
+func oldDB(t *testing.T, stmts ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db.sqlite3")
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, stmt := range append([]string{
+		`CREATE TABLE episodes (id TEXT PRIMARY KEY, show_title TEXT, season INTEGER, episode INTEGER, file_path TEXT)`,
+		`CREATE TABLE progress (show_title TEXT PRIMARY KEY, last_watched_season INTEGER, last_watched_episode INTEGER,
+			progress INTEGER, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE settings (key TEXT PRIMARY KEY, value TEXT, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE folder_hashes (path TEXT PRIMARY KEY, hash TEXT)`,
+		`CREATE TABLE episode_progress (episode_id TEXT PRIMARY KEY, position INTEGER, duration INTEGER,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE watch_history (id INTEGER PRIMARY KEY AUTOINCREMENT, episode_id TEXT, position INTEGER,
+			duration INTEGER, watched_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+	}, stmts...) {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return path
+}
+
+// lostDB is an unversioned database with two episodes of Lost, the second
+// of which is being watched.
+func lostDB(t *testing.T) string {
+	return oldDB(t,
+		`INSERT INTO episodes VALUES ('old1', 'lost', 1, 1, '/tv/Lost/Lost.S01E01.mkv')`,
+		`INSERT INTO episodes VALUES ('old2', 'lost', 1, 2, '/tv/Lost/Lost.S01E02.mkv')`,
+		`INSERT INTO progress (show_title, last_watched_season, last_watched_episode, progress) VALUES ('lost', 1, 2, 300)`,
+		`INSERT INTO episode_progress (episode_id, position, duration) VALUES ('old2', 300, 2400)`,
+		`INSERT INTO watch_history (episode_id, position, duration) VALUES ('old1', 2300, 2400)`,
+	)
+}
+
+func schemaVersion(t *testing.T, db *DB) int {
+	t.Helper()
+	var version int
+	if err := db.Conn.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestMigrateUnversionedDatabase(t *testing.T) {
+	path := lostDB(t)
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer db.Close()
+
+	if v := schemaVersion(t, db); v != SchemaVersion() {
+		t.Errorf("schema version is %d, want %d", v, SchemaVersion())
+	}
+	if _, err := os.Stat(path + ".v0.bak"); err != nil {
+		t.Errorf("no backup of the old database: %v", err)
+	}
+
+	shows, err := db.ListShows("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shows) != 1 || shows[0].LastSeason != 1 || shows[0].LastEpisode != 2 {
+		t.Fatalf("expected progress on S01E02 of the one show, got %+v", shows)
+	}
+	show := shows[0]
+
+	first, err := db.GetEpisode(show.ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Id != episodeID(show.ID, 1, 1) {
+		t.Errorf("episode id %s was not rewritten", first.Id)
+	}
+	if watched, _ := db.IsWatched(first.Id); !watched {
+		t.Error("watch history did not follow the new episode id")
+	}
+	second, err := db.GetEpisode(show.ID, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resume, err := db.GetEpisodeProgress(second.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resume.Position != 300 {
+		t.Errorf("resume point is %d, want 300", resume.Position)
+	}
+
+	eps, err := db.ListEpisodes(show.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eps) != 2 || eps[0].Path != "/tv/Lost/Lost.S01E01.mkv" {
+		t.Errorf("episodes of the show are %+v", eps)
+	}
+}
+
+func TestMigrateReparsesFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite3")
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a database from before the re-parse migration
+	_, err = db.Conn.Exec(`INSERT INTO files (path, size, mtime) VALUES ('/tv/Lost/Lost.S01E01.mkv', 1, 1)`)
+	if err == nil {
+		_, err = db.Conn.Exec(`DELETE FROM schema_version WHERE version = ?`, SchemaVersion())
+	}
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer db.Close()
+	var files int
+	if err := db.Conn.QueryRow(`SELECT COUNT(*) FROM files`).Scan(&files); err != nil {
+		t.Fatal(err)
+	}
+	if files != 0 {
+		t.Errorf("%d files would not be parsed again", files)
+	}
+}
+
+func TestMigrateMergesShowTitles(t *testing.T) {
+	// titles that only differ in punctuation are one show now
+	path := oldDB(t,
+		`INSERT INTO episodes VALUES ('a', 'the office', 1, 1, '/tv/The Office/The.Office.S01E01.mkv')`,
+		`INSERT INTO episodes VALUES ('b', 'the office.', 1, 2, '/tv/The Office/The.Office..S01E02.mkv')`,
+		`INSERT INTO progress VALUES ('the office', 1, 1, 10, '2020-01-01 00:00:00')`,
+		`INSERT INTO progress VALUES ('the office.', 1, 2, 20, '2021-01-01 00:00:00')`,
+	)
+	db, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer db.Close()
+
+	shows, err := db.ListShows("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shows) != 1 {
+		t.Fatalf("expected one show, got %+v", shows)
+	}
+	if shows[0].Episodes != 2 || shows[0].LastEpisode != 2 {
+		t.Errorf("expected both episodes and the latest progress, got %+v", shows[0])
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite3")
+	for i := 0; i < 2; i++ {
+		db, err := InitDB(path)
+		if err != nil {
+			t.Fatalf("InitDB #%d: %v", i+1, err)
+		}
+		db.Close()
+	}
+	matches, _ := filepath.Glob(path + ".v*.bak")
+	if len(matches) != 0 {
+		t.Errorf("backed up an empty database: %v", matches)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	path := oldDB(t,
+		`CREATE TABLE schema_version (version INTEGER PRIMARY KEY, name TEXT, applied_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		fmt.Sprintf(`INSERT INTO schema_version (version, name) VALUES (%d, 'from the future')`, SchemaVersion()+1),
+	)
+	_, err := InitDB(path)
+	if err == nil || !strings.Contains(err.Error(), "please upgrade showtracker") {
+		t.Fatalf("expected an upgrade error, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+const rootColumns = `id, path, enabled, ignore, offline_when_unmounted`
+
+func scanRoot(row interface{ Scan(...interface{}) error }) (model.LibraryRoot, error) {
+	var r model.LibraryRoot
+	var ignore string
+	if err := row.Scan(&r.ID, &r.Path, &r.Enabled, &ignore, &r.OfflineWhenUnmounted); err != nil {
+		return r, err
+	}
+	if ignore != "" {
+		r.Ignore = strings.Split(ignore, "\n")
+	}
+	return r, nil
+}
+
+// Roots returns the library roots in the order they were added.
+func (db *DB) Roots() ([]model.LibraryRoot, error) {
+	rows, err := db.Conn.Query(`SELECT ` + rootColumns + ` FROM library_roots ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query library roots: %w", err)
+	}
+	defer rows.Close()
+
+	var roots []model.LibraryRoot
+	for rows.Next() {
+		r, err := scanRoot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan library root: %w", err)
+		}
+		roots = append(roots, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return roots, nil
+}
+
+// FindRoot returns the library root with the given path or id.
+func (db *DB) FindRoot(pathOrID string) (*model.LibraryRoot, error) {
+	id, _ := strconv.ParseInt(pathOrID, 10, 64)
+	r, err := scanRoot(db.Conn.QueryRow(`
+		SELECT `+rootColumns+` FROM library_roots WHERE path = ? OR id = ?
+	`, filepath.Clean(pathOrID), id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("library root not found: %s", pathOrID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library root %s: %w", pathOrID, err)
+	}
+	return &r, nil
+}
+
+// AddRoot adds a library root and fills in its id.
+func (db *DB) AddRoot(r *model.LibraryRoot) error {
+	r.Path = filepath.Clean(r.Path)
+	err := db.Conn.QueryRow(`
+		INSERT INTO library_roots (path, enabled, ignore, offline_when_unmounted)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`, r.Path, r.Enabled, strings.Join(r.Ignore, "\n"), r.OfflineWhenUnmounted).Scan(&r.ID)
+	if err != nil {
+		return fmt.Errorf("failed to add library root %s: %w", r.Path, err)
+	}
+	return nil
+}
+
+// UpdateRoot saves the settings of a library root.
+func (db *DB) UpdateRoot(r *model.LibraryRoot) error {
+	_, err := db.Conn.Exec(`
+		UPDATE library_roots SET enabled = ?, ignore = ?, offline_when_unmounted = ? WHERE id = ?
+	`, r.Enabled, strings.Join(r.Ignore, "\n"), r.OfflineWhenUnmounted, r.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update library root %s: %w", r.Path, err)
+	}
+	return nil
+}
+
+// RemoveRoot stops scanning a library root. Its episodes stay in the
+// library, with their progress.
+func (db *DB) RemoveRoot(id int64) error {
+	if _, err := db.Conn.Exec(`DELETE FROM library_roots WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to remove library root %d: %w", id, err)
+	}
+	return nil
+}
+
+// SetRootOffline marks the episodes under root unavailable, or available
+// again, and returns how many changed.
+func (db *DB) SetRootOffline(root string, offline bool) (int, error) {
+	return setOffline(db.Conn, root, offline)
+}
+
+func setOffline(q queryer, root string, offline bool) (int, error) {
+	rows, err := q.Query(`SELECT id, file_path FROM episodes WHERE offline = ? AND missing_since IS NULL`, !offline)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query episodes: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id, path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return 0, fmt.Errorf("failed to scan episode: %w", err)
+		}
+		if inRoot(path, root) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if _, err := q.Exec(`UPDATE episodes SET offline = ? WHERE id = ?`, offline, id); err != nil {
+			return 0, fmt.Errorf("failed to update availability of episode %s: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+func TestOfflineRoot(t *testing.T) {
+	db := newTestDB(t)
+	usb := []model.Episode{
+		{Title: "Lost", Season: 1, Episode: 1, Path: "/mnt/usb/Lost/Lost.S01E01.mkv"},
+		{Title: "Lost", Season: 1, Episode: 2, Path: "/mnt/usb/Lost/Lost.S01E02.mkv"},
+	}
+	sync(t, db, "/mnt/usb", usb...)
+	sync(t, db, "/tv", model.Episode{Title: "House", Season: 1, Episode: 1, Path: "/tv/House/House.S01E01.mkv"})
+	lost, err := db.FindShow("Lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	house, err := db.FindShow("House")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := db.SetRootOffline("/mnt/usb", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("%d episodes went offline, want 2", n)
+	}
+	if _, err := db.GetEpisode(lost.ID, 1, 1); err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Errorf("expected S01E01 to be unavailable, got %v", err)
+	}
+	if _, err := db.GetEpisode(house.ID, 1, 1); err != nil {
+		t.Errorf("another root went offline too: %v", err)
+	}
+
+	// plugged back in, the next scan brings them back
+	if changes := sync(t, db, "/mnt/usb", usb...); len(changes.Added) != 0 || len(changes.Removed) != 0 {
+		t.Errorf("remounting changed the library: %+v", changes)
+	}
+	if _, err := db.GetEpisode(lost.ID, 1, 1); err != nil {
+		t.Errorf("S01E01 is still unavailable: %v", err)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	db := newTestDB(t)
+	root := &model.LibraryRoot{Path: "/tv/", Enabled: true, Ignore: []string{"*sample*", "Extras"}}
+	if err := db.AddRoot(root); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{"/tv", "/tv/", "1"} {
+		r, err := db.FindRoot(query)
+		if err != nil {
+			t.Errorf("FindRoot(%q): %v", query, err)
+			continue
+		}
+		if r.Path != "/tv" || strings.Join(r.Ignore, ",") != "*sample*,Extras" {
+			t.Errorf("FindRoot(%q) = %+v", query, r)
+		}
+	}
+	if _, err := db.FindRoot("/movies"); err == nil {
+		t.Error("found a root that was never added")
+	}
+}
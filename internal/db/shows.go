@@ -0,0 +1,213 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// showColumns are the show fields in the order showFields expects, for
+// queries that alias the shows table as s.
+const showColumns = `s.id, s.title, COALESCE(s.sort_title, ''), s.year,
+	COALESCE(s.tmdb_id, 0), COALESCE(s.folder_path, ''), COALESCE(s.numbering, '')`
+
+func showFields(s *model.Show) []interface{} {
+	return []interface{}{&s.ID, &s.Title, &s.SortTitle, &s.Year, &s.TMDBID, &s.FolderPath, &s.Numbering}
+}
+
+// showKey is what two titles must share to be the same show, so
+// "Marvel's Agents of S.H.I.E.L.D." and "marvels agents of shield" match.
+func showKey(title string) string {
+	return strings.Join(strings.Fields(normalizeString(title)), " ")
+}
+
+// sortTitle drops a leading article so "The Office" sorts under O.
+func sortTitle(title string) string {
+	t := strings.ToLower(strings.TrimSpace(title))
+	for _, article := range []string{"the ", "a ", "an "} {
+		if strings.HasPrefix(t, article) && len(t) > len(article) {
+			return strings.TrimSpace(t[len(article):])
+		}
+	}
+	return t
+}
+
+// a show's files are often split in season folders below the show folder
+var seasonFolderRe = regexp.MustCompile(`(?i)^(season|series|s)[ ._-]?\d{1,2}$|^specials$`)
+
+// showFolder guesses the folder of a show from the path of one of its episodes.
+func showFolder(path string) string {
+	if path == "" {
+		return ""
+	}
+	dir := filepath.Dir(path)
+	if seasonFolderRe.MatchString(filepath.Base(dir)) {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// queryer is a *sql.DB or a *sql.Tx.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// saveShow returns the id of a show, creating it the first time it is seen.
+// The display title is updated to the latest casing found on disk. Files
+// without a year join the show of the same name if there is only one, and
+// the first year seen is given to a show that had none.
+func saveShow(q queryer, title string, year int, folder string) (int64, error) {
+	key := showKey(title)
+
+	if year > 0 {
+		_, err := q.Exec(`
+			UPDATE shows SET year = ?
+			WHERE title_key = ? AND year = 0
+			AND NOT EXISTS (SELECT 1 FROM shows WHERE title_key = ? AND year = ?)
+		`, year, key, key, year)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save show %s: %w", title, err)
+		}
+	} else {
+		var id int64
+		err := q.QueryRow(`
+			SELECT MIN(id) FROM shows WHERE title_key = ? HAVING COUNT(*) = 1
+		`, key).Scan(&id)
+		if err == nil {
+			_, err = q.Exec(`
+				UPDATE shows SET title = ?, folder_path = COALESCE(NULLIF(?, ''), folder_path) WHERE id = ?
+			`, title, folder, id)
+			return id, err
+		}
+		if err != sql.ErrNoRows {
+			return 0, fmt.Errorf("failed to look up show %s: %w", title, err)
+		}
+	}
+
+	var id int64
+	err := q.QueryRow(`
+		INSERT INTO shows (title, title_key, sort_title, year, folder_path)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''))
+		ON CONFLICT(title_key, year) DO UPDATE SET
+			title = excluded.title,
+			sort_title = excluded.sort_title,
+			folder_path = COALESCE(excluded.folder_path, shows.folder_path)
+		RETURNING id
+	`, title, key, sortTitle(title), year, folder).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save show %s: %w", title, err)
+	}
+	return id, nil
+}
+
+// GetShow returns a show by id.
+func (db *DB) GetShow(id int64) (*model.Show, error) {
+	var s model.Show
+	err := db.Conn.QueryRow(`
+		SELECT `+showColumns+` FROM shows s WHERE s.id = ?
+	`, id).Scan(showFields(&s)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("show %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get show %d: %w", id, err)
+	}
+	return &s, nil
+}
+
+// FindShow returns the show that best matches query.
+func (db *DB) FindShow(query string) (*model.Show, error) {
+	return db.findBestShowMatch(query)
+}
+
+// Shows returns every show that has episodes in the library, by sort title.
+func (db *DB) Shows() ([]model.Show, error) {
+	return db.queryShows(`
+		SELECT ` + showColumns + `
+		FROM shows s
+		WHERE EXISTS (SELECT 1 FROM episodes e WHERE e.show_id = s.id AND e.missing_since IS NULL)
+		ORDER BY s.sort_title, s.year
+	`)
+}
+
+func (db *DB) queryShows(query string, args ...interface{}) ([]model.Show, error) {
+	rows, err := db.Conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shows: %w", err)
+	}
+	defer rows.Close()
+
+	var shows []model.Show
+	for rows.Next() {
+		var s model.Show
+		if err := rows.Scan(showFields(&s)...); err != nil {
+			return nil, fmt.Errorf("failed to scan show: %w", err)
+		}
+		shows = append(shows, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return shows, nil
+}
+
+// errNoShowMatch is returned when no show is similar enough to a query.
+var errNoShowMatch = errors.New("no similar show found")
+
+// findBestShowMatch finds the best matching show using fuzzy search
+func (db *DB) findBestShowMatch(query string) (*model.Show, error) {
+	shows, err := db.queryShows(`
+		SELECT ` + showColumns + `
+		FROM shows s
+		LEFT JOIN progress p ON p.show_id = s.id
+		WHERE p.show_id IS NOT NULL OR EXISTS (SELECT 1 FROM episodes e WHERE e.show_id = s.id)
+		ORDER BY p.updated_at IS NULL, p.updated_at DESC, s.year DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exact matches first, the most recently watched one wins
+	key := showKey(query)
+	for i, s := range shows {
+		if showKey(s.Title) == key || (s.Year > 0 && showKey(s.Title+" "+strconv.Itoa(s.Year)) == key) {
+			return &shows[i], nil
+		}
+	}
+
+	var best *model.Show
+	var bestScore float64
+	for i, s := range shows {
+		similarity := stringSimilarity(query, s.Title)
+		if s.Year > 0 {
+			similarity = maxFloat(similarity, stringSimilarity(query, s.Title+" "+strconv.Itoa(s.Year)))
+		}
+		if similarity > bestScore && similarity >= minSimilarity {
+			bestScore = similarity
+			best = &shows[i]
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("%w for: %s", errNoShowMatch, query)
+	}
+
+	return best, nil
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
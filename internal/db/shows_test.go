@@ -0,0 +1,136 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+func TestSaveEpisodesGroupsShows(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	save := func(title string, year int) {
+		t.Helper()
+		path := fmt.Sprintf("/tv/%s (%d)/%s.S01E01.mkv", title, year, title)
+		ep := model.Episode{Title: title, Year: year, Season: 1, Episode: 1, Path: path}
+		if err := db.SaveEpisodes([]model.Episode{ep}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("the office", 0)
+	save("The Office", 0)    // same show, the casing on disk wins
+	save("Doctor Who", 2005) // remakes are told apart by year
+	save("Doctor Who", 1963)
+	save("Lost", 0)
+	save("Lost", 2004) // the first year seen goes to the show without one
+
+	shows, err := db.Shows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range shows {
+		got = append(got, fmt.Sprintf("%s (%d)", s.Title, s.Year))
+	}
+	want := "Doctor Who (1963), Doctor Who (2005), Lost (2004), The Office (0)"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("shows are %s, want %s", strings.Join(got, ", "), want)
+	}
+}
+
+func TestEpisodeIDsDontCollide(t *testing.T) {
+	// the old ids hashed the numbers as single bytes, 256 was 0
+	seen := make(map[string]string)
+	for _, c := range [][3]int{{1, 1, 0}, {1, 1, 256}, {1, 256, 1}, {1, 0, 1}, {2, 1, 1}, {11, 1, 1}, {1, 11, 1}} {
+		id := episodeID(int64(c[0]), c[1], c[2])
+		code := fmt.Sprintf("show %d S%02dE%02d", c[0], c[1], c[2])
+		if other, ok := seen[id]; ok {
+			t.Errorf("%s has the id of %s", code, other)
+		}
+		seen[id] = code
+	}
+	if episodeID(1, 1, 1) != episodeID(1, 1, 1) {
+		t.Error("ids are not stable")
+	}
+}
+
+func TestSaveMultiEpisodeFile(t *testing.T) {
+	db := newTestDB(t)
+	err := db.SaveEpisodes([]model.Episode{
+		{Title: "Lost", Season: 1, Episode: 1, EpisodeEnd: 2, Path: "/tv/Lost/Lost.S01E01E02.mkv"},
+		{Title: "Lost", Season: 1, Episode: 3, Path: "/tv/Lost/Lost.S01E03.mkv"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	show, err := db.FindShow("Lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both episodes are in the library, from the same file
+	for _, n := range []int{1, 2} {
+		ep, err := db.GetEpisode(show.ID, 1, n)
+		if err != nil {
+			t.Fatalf("E%02d: %v", n, err)
+		}
+		if ep.Path != "/tv/Lost/Lost.S01E01E02.mkv" {
+			t.Errorf("E%02d is at %s", n, ep.Path)
+		}
+	}
+
+	// and the file plays once
+	next, err := db.GetNextEpisodes(show.ID, 1, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ep := range next {
+		got = append(got, ep.Code())
+	}
+	if fmt.Sprint(got) != "[S01E03]" {
+		t.Errorf("next episodes are %v", got)
+	}
+}
+
+func TestListShowsFilter(t *testing.T) {
+	db := newTestDB(t)
+	err := db.SaveEpisodes([]model.Episode{
+		{Title: "Doctor Who", Year: 1963, Season: 1, Episode: 1, Path: "/tv/Doctor Who (1963)/Doctor.Who.S01E01.mkv"},
+		{Title: "Doctor Who", Year: 2005, Season: 1, Episode: 1, Path: "/tv/Doctor Who (2005)/Doctor.Who.S01E01.mkv"},
+		{Title: "The Office", Season: 1, Episode: 1, Path: "/tv/The Office/The.Office.S01E01.mkv"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same show play would pick
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{"the ofice", "[The Office (0)]"},
+		{"doctor who 1963", "[Doctor Who (1963)]"},
+		{"breaking bad", "[]"},
+	}
+	for _, tt := range tests {
+		shows, err := db.ListShows(tt.filter)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.filter, err)
+		}
+		got := []string{}
+		for _, s := range shows {
+			got = append(got, fmt.Sprintf("%s (%d)", s.Title, s.Year))
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%q: got %v, want %s", tt.filter, got, tt.want)
+		}
+	}
+}
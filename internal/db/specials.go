@@ -0,0 +1,161 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// specialsJoin adds the catalog entry of the specials, c, with where they
+// aired. It is empty for every other episode.
+const specialsJoin = `LEFT JOIN catalog_episodes c
+	ON e.season = 0 AND c.tmdb_show_id = s.tmdb_id AND c.season = 0 AND c.episode = e.episode`
+
+// playOrder is the order a show plays on in: watchOrder, with the specials
+// the catalog placed right before the episode they aired before, and the
+// others before everything.
+const playOrder = `
+	CASE WHEN s.numbering = 'absolute' THEN
+		CASE WHEN c.airs_before_season IS NOT NULL THEN c.airs_before_absolute
+			WHEN e.season = 0 THEN 0
+			ELSE COALESCE(e.absolute, 1000000000) END
+		ELSE 0 END,
+	COALESCE(c.airs_before_season, e.season),
+	COALESCE(c.airs_before_episode, e.episode),
+	CASE WHEN c.airs_before_season IS NULL THEN 1000000 ELSE e.episode END`
+
+// SpecialsPlacement returns the specials setting, SpecialsSkip unless it
+// was set to SpecialsAired.
+func (db *DB) SpecialsPlacement() string {
+	if db.GetSetting("specials") == model.SpecialsAired {
+		return model.SpecialsAired
+	}
+	return model.SpecialsSkip
+}
+
+// skippedSpecials is the condition on the episodes a show doesn't play on
+// to: every special, or with SpecialsAired those the catalog didn't place.
+func skippedSpecials(placement string) string {
+	if placement == model.SpecialsAired {
+		return `(e.season = 0 AND c.airs_before_season IS NULL)`
+	}
+	return `(e.season = 0)`
+}
+
+// playPosition returns the playOrder key of the episode at season and
+// episode, so the following ones can be found.
+func (db *DB) playPosition(showID int64, season, episode int) ([]interface{}, error) {
+	var k1, k2, k3, k4 int64
+	err := db.Conn.QueryRow(`
+		SELECT `+playOrder+`
+		FROM episodes e
+		JOIN shows s ON s.id = e.show_id
+		`+specialsJoin+`
+		WHERE e.show_id = ? AND e.season = ? AND e.episode = ?
+	`, showID, season, episode).Scan(&k1, &k2, &k3, &k4)
+	if err == nil {
+		return []interface{}{k1, k2, k3, k4}, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// the episode is gone, go by the last absolute number before it
+	err = db.Conn.QueryRow(`
+		SELECT CASE WHEN s.numbering = 'absolute' THEN COALESCE(MAX(e.absolute), 0) ELSE 0 END
+		FROM shows s
+		LEFT JOIN episodes e ON e.show_id = s.id AND (e.season < ? OR (e.season = ? AND e.episode <= ?))
+		WHERE s.id = ?
+	`, season, season, episode, showID).Scan(&k1)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return []interface{}{k1, season, episode, 1000000}, nil
+}
+
+// GetNextSpecials returns the specials of a show after special episode, in
+// order, for playing them on their own.
+func (db *DB) GetNextSpecials(showID int64, episode int, count int) ([]*model.Episode, error) {
+	return db.queryEpisodes(`
+		SELECT `+episodeColumns+`
+		FROM episodes e
+		JOIN shows s ON s.id = e.show_id
+		WHERE e.show_id = ? AND e.season = 0 AND e.episode > ? AND `+episodeAvailable+`
+		AND NOT EXISTS (
+			SELECT 1 FROM episodes o
+			WHERE o.show_id = e.show_id AND o.season = 0 AND o.file_path = e.file_path
+			AND o.episode < e.episode AND o.missing_since IS NULL
+		)
+		ORDER BY e.episode
+		LIMIT ?
+	`, showID, episode, count)
+}
+
+// NextSpecial returns the special after the last one watched of a show, or
+// the first one.
+func (db *DB) NextSpecial(showID int64) (*model.Episode, error) {
+	var last int
+	err := db.Conn.QueryRow(`
+		SELECT COALESCE(MAX(e.episode), 0)
+		FROM watch_history h
+		JOIN episodes e ON e.id = h.episode_id
+		WHERE e.show_id = ? AND e.season = 0
+	`, showID).Scan(&last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watched specials: %w", err)
+	}
+
+	eps, err := db.GetNextSpecials(showID, last, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(eps) == 0 {
+		if last > 0 {
+			return nil, fmt.Errorf("no specials left after S00E%02d", last)
+		}
+		return nil, fmt.Errorf("no specials in the library")
+	}
+	return eps[0], nil
+}
+
+// airsBefore works out from the air dates which regular episode each
+// special of a catalog aired right before, as season, episode and absolute
+// number, since TMDB doesn't say. Specials that aired after the last one
+// go before the season that comes next, those without a date nowhere.
+func airsBefore(eps []model.Episode) map[int][3]int {
+	var regular []model.Episode
+	for _, ep := range eps {
+		if ep.Season > 0 {
+			regular = append(regular, ep)
+		}
+	}
+	if len(regular) == 0 {
+		return nil
+	}
+	sort.Slice(regular, func(i, j int) bool {
+		if regular[i].Season != regular[j].Season {
+			return regular[i].Season < regular[j].Season
+		}
+		return regular[i].Episode < regular[j].Episode
+	})
+	last := regular[len(regular)-1]
+	after := [3]int{last.Season + 1, 0, len(regular) + 1}
+
+	placed := make(map[int][3]int)
+	for _, sp := range eps {
+		if sp.Season != 0 || sp.AirDate == "" {
+			continue
+		}
+		placed[sp.Episode] = after
+		for i, ep := range regular {
+			// YYYY-MM-DD compares as text
+			if ep.AirDate != "" && ep.AirDate > sp.AirDate {
+				placed[sp.Episode] = [3]int{ep.Season, ep.Episode, i + 1}
+				break
+			}
+		}
+	}
+	return placed
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// lostLibrary has two specials, season 1 and the first episode of season 2.
+func lostLibrary(t *testing.T) (*DB, int64) {
+	t.Helper()
+	db := newTestDB(t)
+	var eps []model.Episode
+	for _, se := range [][2]int{{0, 1}, {0, 2}, {1, 1}, {1, 2}, {1, 3}, {2, 1}} {
+		eps = append(eps, model.Episode{Title: "Lost", Season: se[0], Episode: se[1],
+			Path: fmt.Sprintf("/tv/Lost/Lost.S%02dE%02d.mkv", se[0], se[1])})
+	}
+	sync(t, db, "/tv", eps...)
+	show, err := db.FindShow("Lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, show.ID
+}
+
+func codes(eps []*model.Episode) []string {
+	var out []string
+	for _, ep := range eps {
+		out = append(out, ep.Code())
+	}
+	return out
+}
+
+func TestSpecialsAreSkipped(t *testing.T) {
+	db, showID := lostLibrary(t)
+
+	first, err := db.FindLatestWatchedEpisode("Lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Code() != "S01E01" {
+		t.Errorf("a new show starts on %s, want S01E01", first.Code())
+	}
+
+	next, err := db.GetNextEpisodes(showID, 1, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(codes(next)); got != "[S02E01]" {
+		t.Errorf("after S01E03 come %s", got)
+	}
+}
+
+func TestProgressOnSpecialContinuesWithShow(t *testing.T) {
+	db, showID := lostLibrary(t)
+	if err := db.SaveProgress(showID, 0, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := db.FindLatestWatchedEpisode("Lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := db.GetNextEpisodes(showID, latest.Season, latest.Episode, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(codes(next)); got != "[S01E01 S01E02]" {
+		t.Errorf("after S00E01 come %s, want S01E01 S01E02", got)
+	}
+}
+
+func TestSpecialsPlacedWhereTheyAired(t *testing.T) {
+	db, showID := lostLibrary(t)
+	if err := db.SetSetting("specials", model.SpecialsAired); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetShowTMDBID(showID, 4607); err != nil {
+		t.Fatal(err)
+	}
+	err := db.SaveCatalog(4607, []model.Episode{
+		{Season: 0, Episode: 1, AirDate: "2004-09-01"},
+		{Season: 0, Episode: 2, AirDate: "2005-03-01"},
+		{Season: 1, Episode: 1, AirDate: "2004-09-22"},
+		{Season: 1, Episode: 2, AirDate: "2004-09-29"},
+		{Season: 1, Episode: 3, AirDate: "2004-10-06"},
+		{Season: 2, Episode: 1, AirDate: "2005-09-21"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := db.FindLatestWatchedEpisode("Lost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Code() != "S00E01" {
+		t.Errorf("the show starts on %s, want the special that aired first", first.Code())
+	}
+	next, err := db.GetNextEpisodes(showID, 1, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(codes(next)); got != "[S01E03 S00E02 S02E01]" {
+		t.Errorf("after S01E02 come %s", got)
+	}
+}
+
+func TestNextSpecial(t *testing.T) {
+	db, showID := lostLibrary(t)
+	ep, err := db.NextSpecial(showID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Code() != "S00E01" {
+		t.Fatalf("first special is %s", ep.Code())
+	}
+	if err := db.RecordWatch(ep.Id, 90, 100); err != nil {
+		t.Fatal(err)
+	}
+	if ep, err = db.NextSpecial(showID); err != nil || ep.Code() != "S00E02" {
+		t.Fatalf("after S00E01 got %v, %v", ep, err)
+	}
+}
@@ -1,9 +1,13 @@
 package db
 
 import (
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -11,10 +15,35 @@ import (
 	"github.com/yoooby/showtrack/internal/model"
 )
 
+// DefaultCompletionThreshold is used when no completion_threshold setting is stored.
+const DefaultCompletionThreshold = 0.9
+
 type DB struct {
 	Conn *sql.DB
 }
 
+// episodeColumns are the episode fields in the order episodeFields expects,
+// for queries that alias the episodes table as e and join its show as s.
+const episodeColumns = `e.id, e.show_id, s.title, s.year, e.season, e.episode, COALESCE(e.episode_end, 0), COALESCE(e.absolute, 0), COALESCE(e.date, ''), e.file_path,
+	COALESCE(s.tmdb_id, 0), COALESCE(e.name, ''), COALESCE(e.air_date, ''), COALESCE(e.runtime, 0)`
+
+// episodeAvailable keeps the episodes whose file can be played: not gone
+// from disk and not on a library root that is offline.
+const episodeAvailable = `e.missing_since IS NULL AND e.offline = 0`
+
+func episodeFields(ep *model.Episode) []interface{} {
+	return []interface{}{&ep.Id, &ep.ShowID, &ep.Title, &ep.Year, &ep.Season, &ep.Episode, &ep.EpisodeEnd, &ep.Absolute, &ep.Date, &ep.Path,
+		&ep.TMDBShowID, &ep.Name, &ep.AirDate, &ep.Runtime}
+}
+
+// episodeID is the primary key of an episode. The show id tells apart shows
+// that share a title, and the numbers are written out in full so no two
+// episodes hash the same input.
+func episodeID(showID int64, season, episode int) string {
+	h := sha1.Sum([]byte(fmt.Sprintf("show:%d/season:%d/episode:%d", showID, season, episode)))
+	return hex.EncodeToString(h[:])
+}
+
 // Fuzzy search utilities
 func levenshteinDistance(s1, s2 string) int {
 	r1, r2 := []rune(s1), []rune(s2)
@@ -88,51 +117,7 @@ func max(a, b int) int {
 	return b
 }
 
-// findBestShowMatch finds the best matching show title using fuzzy search
-func (db *DB) findBestShowMatch(query string) (string, error) {
-	// First try to find exact match in progress table
-	var exactMatch string
-	err := db.Conn.QueryRow(`
-		SELECT show_title FROM progress WHERE show_title = ?
-	`, strings.ToLower(query)).Scan(&exactMatch)
-	if err == nil {
-		return exactMatch, nil
-	}
-
-	// If no exact match, get all show titles and find best fuzzy match
-	rows, err := db.Conn.Query(`
-		SELECT DISTINCT show_title FROM progress
-		UNION
-		SELECT DISTINCT show_title FROM episodes
-	`)
-	if err != nil {
-		return "", fmt.Errorf("failed to query show titles: %w", err)
-	}
-	defer rows.Close()
-
-	var bestMatch string
-	var bestScore float64
-	const minSimilarity = 0.6 // Minimum similarity threshold
-
-	for rows.Next() {
-		var title string
-		if err := rows.Scan(&title); err != nil {
-			continue
-		}
-
-		similarity := stringSimilarity(query, title)
-		if similarity > bestScore && similarity >= minSimilarity {
-			bestScore = similarity
-			bestMatch = title
-		}
-	}
-
-	if bestMatch == "" {
-		return "", fmt.Errorf("no similar show found for: %s", query)
-	}
-
-	return bestMatch, nil
-}
+const minSimilarity = 0.6 // Minimum similarity threshold for fuzzy show matching
 
 func (db *DB) GetSetting(key string) string {
 	var value string
@@ -169,15 +154,15 @@ func (db *DB) SetSetting(key, value string) error {
 }
 
 func (db *DB) FindLatestWatchedEpisodeGlobal() (*model.Episode, error) {
-	var title string
+	var showID int64
 	var season, episode int
 
 	err := db.Conn.QueryRow(`
-		SELECT show_title, last_watched_season, last_watched_episode
+		SELECT show_id, last_watched_season, last_watched_episode
 		FROM progress
 		ORDER BY updated_at DESC
 		LIMIT 1
-	`).Scan(&title, &season, &episode)
+	`).Scan(&showID, &season, &episode)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -185,7 +170,7 @@ func (db *DB) FindLatestWatchedEpisodeGlobal() (*model.Episode, error) {
 		}
 		return nil, fmt.Errorf("failed to query latest watched episode: %w", err)
 	}
-	return db.GetEpisode(title, season, episode)
+	return db.GetEpisode(showID, season, episode)
 }
 
 func InitDB(path string) (*DB, error) {
@@ -194,91 +179,157 @@ func InitDB(path string) (*DB, error) {
 		return nil, err
 	}
 
-	// create tables
-	_, err = conn.Exec(`
-        CREATE TABLE IF NOT EXISTS episodes (
-            id TEXT PRIMARY KEY,
-            show_title TEXT,
-            season INTEGER,
-            episode INTEGER,
-            file_path TEXT
-        )
-    `)
-	if err != nil {
+	if err := migrate(conn, path); err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	_, err = conn.Exec(`
-        CREATE TABLE IF NOT EXISTS progress (
-            show_title TEXT PRIMARY KEY,
-            last_watched_season INTEGER,
-            last_watched_episode INTEGER,
-            progress INTEGER,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-        )
-    `)
-	if err != nil {
-		return nil, err
-	}
+	return &DB{Conn: conn}, nil
+}
 
-	_, err = conn.Exec(`
-		CREATE TABLE IF NOT EXISTS settings (
-			key TEXT PRIMARY KEY,
-			value TEXT,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-	if err != nil {
-		return nil, err
-	}
-	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS folder_hashes (
-		path TEXT PRIMARY KEY,
-		hash TEXT
-	)
-	`)
-	return &DB{Conn: conn}, err
+func (db *DB) Close() error {
+	return db.Conn.Close()
 }
 
+// SaveEpisodes adds scanned episodes to the library, filling in their show
+// and episode ids. A file holding several episodes is saved once for each,
+// the id is the one of the first.
 func (db *DB) SaveEpisodes(eps []model.Episode) error {
 	tx, err := db.Conn.Begin()
 	if err != nil {
 		return err
 	}
+	if _, err := saveEpisodes(tx, eps); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
 
+// saveEpisodes saves eps and returns the absolute numbered ones it couldn't
+// put at their catalog slot because another file already is that episode.
+// Those are kept at their absolute number, or left out with an empty Id
+// when that is the same slot.
+func saveEpisodes(tx *sql.Tx, eps []model.Episode) ([]string, error) {
 	stmt, err := tx.Prepare(`
-        INSERT INTO episodes (id, show_title, season, episode, file_path)
-        VALUES (?, ?, ?, ?, ?)
+        INSERT INTO episodes (id, show_id, season, episode, episode_end, absolute, date, file_path, file_size, file_hash)
+        VALUES (?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, ''), ?, ?, NULLIF(?, ''))
         ON CONFLICT(id) DO UPDATE SET
-            file_path=excluded.file_path
+            show_id=excluded.show_id,
+            episode_end=excluded.episode_end,
+            absolute=excluded.absolute,
+            date=excluded.date,
+            file_path=excluded.file_path,
+            file_size=excluded.file_size,
+            file_hash=COALESCE(excluded.file_hash, episodes.file_hash),
+            missing_since=NULL,
+            offline=0
     `)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer stmt.Close()
 
-	for _, ep := range eps {
-		_, err := stmt.Exec(ep.Id, strings.ToLower(ep.Title), ep.Season, ep.Episode, ep.Path)
-		if err != nil {
-			tx.Rollback()
-			return err
+	shows := make(map[string]int64)
+	absolute := make(map[int64][][2]int) // catalog order of the shows with absolute numbered files
+	var conflicts []string
+
+	// files numbered by season first, so absolute numbered ones find the
+	// slots those take
+	var seasonFirst, absoluteLast []int
+	for i, ep := range eps {
+		if ep.Absolute > 0 {
+			absoluteLast = append(absoluteLast, i)
+		} else {
+			seasonFirst = append(seasonFirst, i)
+		}
+	}
+	for _, i := range append(seasonFirst, absoluteLast...) {
+		ep := &eps[i]
+		key := showKey(ep.Title) + "\x00" + strconv.Itoa(ep.Year)
+		showID, ok := shows[key]
+		if !ok {
+			showID, err = saveShow(tx, ep.Title, ep.Year, showFolder(ep.Path))
+			if err != nil {
+				return nil, err
+			}
+			shows[key] = showID
+		}
+		ep.ShowID = showID
+
+		if ep.Absolute > 0 {
+			order, ok := absolute[showID]
+			if !ok {
+				if order, err = absoluteSeasons(tx, showID); err != nil {
+					return nil, err
+				}
+				absolute[showID] = order
+				// the parser only gives absolute numbers to files outside season folders
+				_, err = tx.Exec(`UPDATE shows SET numbering = ? WHERE id = ? AND numbering IS NULL`, model.AbsoluteNumbering, showID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to set numbering of %s: %w", ep.Title, err)
+				}
+			}
+			if ep.Absolute <= len(order) {
+				season, episode := order[ep.Absolute-1][0], order[ep.Absolute-1][1]
+				taken, err := slotTaken(tx, episodeID(showID, season, episode), ep.Path)
+				if err != nil {
+					return nil, err
+				}
+				switch {
+				case !taken:
+					ep.Season, ep.Episode = season, episode
+				case ep.Season == season && ep.Episode == episode:
+					// where it is would be the same, leave the other file be
+					conflicts = append(conflicts, fmt.Sprintf("absolute episode %d is S%02dE%02d, which another file already is, not added: %s", ep.Absolute, season, episode, ep.Path))
+					ep.Id = ""
+					continue
+				default:
+					// keep it where it is rather than lose the other file
+					conflicts = append(conflicts, fmt.Sprintf("absolute episode %d is S%02dE%02d, which another file already is: %s", ep.Absolute, season, episode, ep.Path))
+				}
+			}
+		}
+		ep.Id = episodeID(showID, ep.Season, ep.Episode)
+
+		end := 0
+		if ep.EpisodeEnd > ep.Episode {
+			end = ep.EpisodeEnd
+		}
+		for n := ep.Episode; n <= max(ep.Episode, end); n++ {
+			_, err := stmt.Exec(episodeID(showID, ep.Season, n), showID, ep.Season, n, end, ep.Absolute, ep.Date, ep.Path, ep.Size, ep.Hash)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	tx.Commit()
+	return conflicts, nil
+}
 
-	return err
+// slotTaken reports whether episode id is in the library from another file
+// than path.
+func slotTaken(q queryer, id, path string) (bool, error) {
+	var n int
+	err := q.QueryRow(`
+		SELECT COUNT(*) FROM episodes WHERE id = ? AND file_path != ? AND missing_since IS NULL
+	`, id, path).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up episode %s: %w", id, err)
+	}
+	return n > 0, nil
 }
 
-func (db *DB) SaveProgress(show string, season, episode, progress int) error {
+func (db *DB) SaveProgress(showID int64, season, episode, progress int) error {
 	_, err := db.Conn.Exec(`
-        INSERT INTO progress (show_title, last_watched_season, last_watched_episode, progress, updated_at)
+        INSERT INTO progress (show_id, last_watched_season, last_watched_episode, progress, updated_at)
         VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
-        ON CONFLICT(show_title) DO UPDATE SET
+        ON CONFLICT(show_id) DO UPDATE SET
             last_watched_season = excluded.last_watched_season,
             last_watched_episode = excluded.last_watched_episode,
             progress = excluded.progress,
 			updated_at = CURRENT_TIMESTAMP
-    `, show, season, episode, progress)
+    `, showID, season, episode, progress)
 	if err != nil {
 		return err
 	}
@@ -286,24 +337,47 @@ func (db *DB) SaveProgress(show string, season, episode, progress int) error {
 	return nil
 }
 
-func (db *DB) GetNextEpisodes(title string, season int, episode int, count int) ([]*model.Episode, error) {
-	rows, err := db.Conn.Query(`
-        SELECT id, show_title, season, episode, file_path
-        FROM episodes
-        WHERE show_title = ?
-        AND (season > ? OR (season = ? AND episode > ?))
-        ORDER BY season ASC, episode ASC
+// GetNextEpisodes returns the episodes after season and episode in watching
+// order, by absolute number for shows numbered that way. Specials are left
+// out unless the specials setting places them where they aired. A special
+// without a place is followed by the start of the show.
+func (db *DB) GetNextEpisodes(showID int64, season int, episode int, count int) ([]*model.Episode, error) {
+	pos, err := db.playPosition(showID, season, episode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find episode S%02dE%02d: %w", season, episode, err)
+	}
+	placement := db.SpecialsPlacement()
+
+	return db.queryEpisodes(`
+        SELECT `+episodeColumns+`
+        FROM episodes e
+        JOIN shows s ON s.id = e.show_id
+        `+specialsJoin+`
+        WHERE e.show_id = ? AND `+episodeAvailable+` AND NOT `+skippedSpecials(placement)+`
+        AND (`+playOrder+`) > (?, ?, ?, ?)
+        AND NOT EXISTS (
+            -- the rest of a multi-episode file plays with its first episode
+            SELECT 1 FROM episodes o
+            WHERE o.show_id = e.show_id AND o.season = e.season AND o.file_path = e.file_path
+            AND o.episode < e.episode AND o.missing_since IS NULL
+        )
+        ORDER BY `+playOrder+`
         LIMIT ?
-    `, title, season, season, episode, count)
+    `, append(append([]interface{}{showID}, pos...), count)...)
+}
+
+// queryEpisodes runs a query selecting episodeColumns.
+func (db *DB) queryEpisodes(query string, args ...interface{}) ([]*model.Episode, error) {
+	rows, err := db.Conn.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query next episodes: %w", err)
+		return nil, fmt.Errorf("failed to query episodes: %w", err)
 	}
 	defer rows.Close()
 
 	var episodes []*model.Episode
 	for rows.Next() {
 		ep := &model.Episode{}
-		if err := rows.Scan(&ep.Id, &ep.Title, &ep.Season, &ep.Episode, &ep.Path); err != nil {
+		if err := rows.Scan(episodeFields(ep)...); err != nil {
 			return nil, fmt.Errorf("failed to scan episode: %w", err)
 		}
 		episodes = append(episodes, ep)
@@ -316,45 +390,190 @@ func (db *DB) GetNextEpisodes(title string, season int, episode int, count int)
 	return episodes, nil
 }
 
-func (db *DB) GetProgress(title string) (int, error) {
-	var ts int
+// SaveEpisodeProgress stores the resume point of a single episode.
+func (db *DB) SaveEpisodeProgress(episodeID string, position, duration int) error {
+	_, err := db.Conn.Exec(`
+		INSERT INTO episode_progress (episode_id, position, duration, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(episode_id) DO UPDATE SET
+			position = excluded.position,
+			duration = excluded.duration,
+			updated_at = CURRENT_TIMESTAMP
+	`, episodeID, position, duration)
+	if err != nil {
+		return fmt.Errorf("failed to save progress for episode %s: %w", episodeID, err)
+	}
+	return nil
+}
+
+// GetEpisodeProgress returns the resume point of an episode, zero valued if it was never played.
+func (db *DB) GetEpisodeProgress(episodeID string) (model.EpisodeProgress, error) {
+	p := model.EpisodeProgress{EpisodeID: episodeID}
 	err := db.Conn.QueryRow(`
-        SELECT progress
-        FROM progress
-        WHERE show_title = ?
-    `, title).Scan(&ts)
+		SELECT position, duration, updated_at
+		FROM episode_progress
+		WHERE episode_id = ?
+	`, episodeID).Scan(&p.Position, &p.Duration, &p.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, nil // no progress saved yet
+			return p, nil
 		}
-		return 0, fmt.Errorf("failed to get progress for %s: %w", title, err)
+		return p, fmt.Errorf("failed to get progress for episode %s: %w", episodeID, err)
 	}
-	return ts, nil
+	return p, nil
 }
 
-func (db *DB) FindLatestWatchedEpisode(query string) (*model.Episode, error) {
-	var bestMatch string
-	err := db.Conn.QueryRow(`
-		SELECT show_title FROM progress WHERE show_title = ?
-	`, query).Scan(&bestMatch)
-
+// RecordWatch adds an episode to the watch history.
+func (db *DB) RecordWatch(episodeID string, position, duration int) error {
+	_, err := db.Conn.Exec(`
+		INSERT INTO watch_history (episode_id, position, duration, watched_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	`, episodeID, position, duration)
 	if err != nil {
-		if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to record watch for episode %s: %w", episodeID, err)
+	}
+	return nil
+}
+
+// ListHistory returns the most recent watches first. An empty show lists every show.
+func (db *DB) ListHistory(show string, limit int) ([]model.WatchEntry, error) {
+	query := `
+		SELECT ` + episodeColumns + `, h.position, h.duration, h.watched_at
+		FROM watch_history h
+		JOIN episodes e ON e.id = h.episode_id
+		JOIN shows s ON s.id = e.show_id
+	`
+	var args []interface{}
+	if show != "" {
+		match, err := db.findBestShowMatch(show)
+		if err != nil {
 			return nil, err
 		}
-		// No exact match, use fuzzy search
-		bestMatch, err = db.findBestShowMatch(query)
-		if err != nil {
+		query += " WHERE e.show_id = ?"
+		args = append(args, match.ID)
+	}
+	query += " ORDER BY h.watched_at DESC, h.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watch history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []model.WatchEntry
+	for rows.Next() {
+		var w model.WatchEntry
+		ep := &w.Episode
+		if err := rows.Scan(append(episodeFields(ep), &w.Position, &w.Duration, &w.WatchedAt)...); err != nil {
+			return nil, fmt.Errorf("failed to scan watch entry: %w", err)
+		}
+		history = append(history, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return history, nil
+}
+
+// IsWatched reports whether an episode was ever watched to completion.
+func (db *DB) IsWatched(episodeID string) (bool, error) {
+	var watched bool
+	err := db.Conn.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM watch_history WHERE episode_id = ?)
+	`, episodeID).Scan(&watched)
+	if err != nil {
+		return false, fmt.Errorf("failed to check watch history for episode %s: %w", episodeID, err)
+	}
+	return watched, nil
+}
+
+// CompletionThreshold is the fraction of an episode that has to be played
+// before it counts as watched. Stored as a percentage in the settings table.
+func (db *DB) CompletionThreshold() float64 {
+	if v, err := strconv.ParseFloat(db.GetSetting("completion_threshold"), 64); err == nil && v > 0 && v <= 100 {
+		return v / 100
+	}
+	return DefaultCompletionThreshold
+}
+
+// ListShows summarizes every show in the library. A non-empty filter keeps
+// only the show that best matches it, as FindShow finds it.
+func (db *DB) ListShows(filter string) ([]model.ShowSummary, error) {
+	var match *model.Show
+	if filter != "" {
+		var err error
+		if match, err = db.findBestShowMatch(filter); err != nil {
+			if errors.Is(err, errNoShowMatch) {
+				return nil, nil
+			}
 			return nil, err
 		}
 	}
 
+	rows, err := db.Conn.Query(`
+		SELECT s.id, s.title, COALESCE(s.sort_title, ''), s.year,
+			COUNT(DISTINCT e.season), COUNT(*),
+			p.last_watched_season, p.last_watched_episode, p.updated_at,
+			(SELECT COALESCE(l.date, '') FROM episodes l
+				WHERE l.show_id = s.id AND l.season = p.last_watched_season AND l.episode = p.last_watched_episode),
+			SUM(CASE
+				WHEN p.show_id IS NULL THEN 1
+				WHEN e.season > p.last_watched_season THEN 1
+				WHEN e.season = p.last_watched_season AND e.episode > p.last_watched_episode THEN 1
+				ELSE 0
+			END)
+		FROM episodes e
+		JOIN shows s ON s.id = e.show_id
+		LEFT JOIN progress p ON p.show_id = e.show_id
+		WHERE e.missing_since IS NULL
+		GROUP BY s.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shows: %w", err)
+	}
+	defer rows.Close()
+
+	var shows []model.ShowSummary
+	for rows.Next() {
+		var s model.ShowSummary
+		var lastSeason, lastEpisode sql.NullInt64
+		var lastWatched sql.NullTime
+		var lastDate sql.NullString
+		if err := rows.Scan(&s.ID, &s.Title, &s.SortTitle, &s.Year, &s.Seasons, &s.Episodes, &lastSeason, &lastEpisode, &lastWatched, &lastDate, &s.Remaining); err != nil {
+			return nil, fmt.Errorf("failed to scan show: %w", err)
+		}
+		if match != nil && s.ID != match.ID {
+			continue
+		}
+		s.LastSeason = int(lastSeason.Int64)
+		s.LastEpisode = int(lastEpisode.Int64)
+		s.LastWatched = lastWatched.Time
+		s.LastDate = lastDate.String
+		shows = append(shows, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return shows, nil
+}
+
+func (db *DB) FindLatestWatchedEpisode(query string) (*model.Episode, error) {
+	show, err := db.findBestShowMatch(query)
+	if err != nil {
+		return nil, err
+	}
+
 	var season, episode int
 	err = db.Conn.QueryRow(`
 		SELECT last_watched_season, last_watched_episode
 		FROM progress
-		WHERE show_title = ?
-	`, bestMatch).Scan(&season, &episode)
+		WHERE show_id = ?
+	`, show.ID).Scan(&season, &episode)
 
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
@@ -362,19 +581,22 @@ func (db *DB) FindLatestWatchedEpisode(query string) (*model.Episode, error) {
 
 	var ep model.Episode
 	if err == nil {
-		return db.GetEpisode(query, season, episode)
+		return db.GetEpisode(show.ID, season, episode)
 	}
 
+	// a show that only has specials starts on them
 	err = db.Conn.QueryRow(`
-		SELECT id, show_title, season, episode, file_path
-		FROM episodes
-		WHERE show_title = ?
-		ORDER BY season ASC, episode ASC
+		SELECT `+episodeColumns+`
+		FROM episodes e
+		JOIN shows s ON s.id = e.show_id
+		`+specialsJoin+`
+		WHERE e.show_id = ? AND `+episodeAvailable+`
+		ORDER BY `+skippedSpecials(db.SpecialsPlacement())+`, `+playOrder+`
 		LIMIT 1
-	`, bestMatch).Scan(&ep.Id, &ep.Title, &ep.Season, &ep.Episode, &ep.Path)
+	`, show.ID).Scan(episodeFields(&ep)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("show not found: %s", bestMatch)
+			return nil, fmt.Errorf("show not found: %s", show.Title)
 		}
 		return nil, err
 	}
@@ -382,20 +604,38 @@ func (db *DB) FindLatestWatchedEpisode(query string) (*model.Episode, error) {
 	return &ep, nil
 }
 
-func (db *DB) GetEpisode(title string, season int, episode int) (*model.Episode, error) {
+func (db *DB) GetEpisode(showID int64, season int, episode int) (*model.Episode, error) {
+	return db.getEpisode(fmt.Sprintf("S%02dE%02d", season, episode),
+		`e.show_id = ? AND e.season = ? AND e.episode = ?`, showID, season, episode)
+}
+
+// GetEpisodeByDate returns the episode of a daily show that aired on date,
+// YYYY-MM-DD.
+func (db *DB) GetEpisodeByDate(showID int64, date string) (*model.Episode, error) {
+	return db.getEpisode(date, `e.show_id = ? AND e.date = ?`, showID, date)
+}
+
+// getEpisode returns the episode matching where, which is called code in
+// errors.
+func (db *DB) getEpisode(code, where string, args ...interface{}) (*model.Episode, error) {
 	var ep model.Episode
+	var offline bool
 	err := db.Conn.QueryRow(`
-		SELECT id, show_title, season, episode, file_path
-		FROM episodes
-		WHERE show_title = ? AND season = ? AND episode = ?
-	`, title, season, episode).Scan(&ep.Id, &ep.Title, &ep.Season, &ep.Episode, &ep.Path)
+		SELECT `+episodeColumns+`, e.offline
+		FROM episodes e
+		JOIN shows s ON s.id = e.show_id
+		WHERE `+where+` AND e.missing_since IS NULL
+	`, args...).Scan(append(episodeFields(&ep), &offline)...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("episode not found: %s S%dE%d", title, season, episode)
+			return nil, fmt.Errorf("episode not found: %s", code)
 		}
 		return nil, err
 	}
+	if offline {
+		return nil, fmt.Errorf("%s %s is unavailable, its library root is not mounted: %s", ep.Title, code, ep.Path)
+	}
 
 	return &ep, nil
 }
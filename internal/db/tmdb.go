@@ -0,0 +1,217 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// TMDBCache stores TMDB responses in the database.
+type TMDBCache struct {
+	db  *DB
+	TTL time.Duration // zero keeps responses forever
+}
+
+// TMDBCache returns a response cache whose entries expire after ttl.
+func (db *DB) TMDBCache(ttl time.Duration) *TMDBCache {
+	return &TMDBCache{db: db, TTL: ttl}
+}
+
+// Get returns a cached response that has not expired.
+func (c *TMDBCache) Get(key string) ([]byte, bool) {
+	body, fetchedAt, ok := c.lookup(key)
+	if !ok || (c.TTL > 0 && time.Since(fetchedAt) > c.TTL) {
+		return nil, false
+	}
+	return body, true
+}
+
+// GetStale returns a cached response even if it expired, for when TMDB
+// can't be reached.
+func (c *TMDBCache) GetStale(key string) ([]byte, bool) {
+	body, _, ok := c.lookup(key)
+	return body, ok
+}
+
+func (c *TMDBCache) lookup(key string) ([]byte, time.Time, bool) {
+	var body []byte
+	var fetchedAt time.Time
+	err := c.db.Conn.QueryRow(`
+		SELECT body, fetched_at FROM tmdb_cache WHERE key = ?
+	`, key).Scan(&body, &fetchedAt)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	return body, fetchedAt, true
+}
+
+func (c *TMDBCache) Set(key string, body []byte) error {
+	_, err := c.db.Conn.Exec(`
+		INSERT INTO tmdb_cache (key, body, fetched_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(key) DO UPDATE SET
+			body = excluded.body,
+			fetched_at = CURRENT_TIMESTAMP
+	`, key, body)
+	if err != nil {
+		return fmt.Errorf("failed to cache %s: %w", key, err)
+	}
+	return nil
+}
+
+// ListEpisodes returns every episode of a show in watching order, leaving
+// out the ones whose file is gone.
+func (db *DB) ListEpisodes(showID int64) ([]model.Episode, error) {
+	rows, err := db.Conn.Query(`
+		SELECT `+episodeColumns+`
+		FROM episodes e
+		JOIN shows s ON s.id = e.show_id
+		WHERE e.show_id = ? AND e.missing_since IS NULL
+		ORDER BY `+watchOrder+`
+	`, showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query episodes: %w", err)
+	}
+	defer rows.Close()
+
+	var episodes []model.Episode
+	for rows.Next() {
+		var ep model.Episode
+		if err := rows.Scan(episodeFields(&ep)...); err != nil {
+			return nil, fmt.Errorf("failed to scan episode: %w", err)
+		}
+		episodes = append(episodes, ep)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return episodes, nil
+}
+
+// SetShowTMDBID matches a show to a TMDB show.
+func (db *DB) SetShowTMDBID(showID int64, id int) error {
+	_, err := db.Conn.Exec(`
+		UPDATE shows SET tmdb_id = ? WHERE id = ?
+	`, id, showID)
+	if err != nil {
+		return fmt.Errorf("failed to set TMDB id for show %d: %w", showID, err)
+	}
+	return nil
+}
+
+// SaveEpisodeMetadata stores the TMDB name, air date and runtime of eps.
+func (db *DB) SaveEpisodeMetadata(eps []model.Episode) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		UPDATE episodes
+		SET name = ?, air_date = ?, runtime = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, ep := range eps {
+		_, err := stmt.Exec(ep.Name, ep.AirDate, ep.Runtime, ep.Id)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save metadata for %s S%02dE%02d: %w", ep.Title, ep.Season, ep.Episode, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SaveCatalog replaces the official episode list of a TMDB show, placing
+// its specials by air date.
+func (db *DB) SaveCatalog(showID int, eps []model.Episode) error {
+	tx, err := db.Conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM catalog_episodes WHERE tmdb_show_id = ?`, showID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to clear catalog of %d: %w", showID, err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO catalog_episodes (tmdb_show_id, season, episode, name, air_date, runtime,
+			airs_before_season, airs_before_episode, airs_before_absolute)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	placed := airsBefore(eps)
+	for _, ep := range eps {
+		var before [3]interface{} // NULL for everything but placed specials
+		if p, ok := placed[ep.Episode]; ok && ep.Season == 0 {
+			before = [3]interface{}{p[0], p[1], p[2]}
+		}
+		if _, err := stmt.Exec(showID, ep.Season, ep.Episode, ep.Name, ep.AirDate, ep.Runtime, before[0], before[1], before[2]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save catalog episode S%02dE%02d: %w", ep.Season, ep.Episode, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListCatalog returns the official episode list of a TMDB show in airing order.
+func (db *DB) ListCatalog(showID int) ([]model.Episode, error) {
+	rows, err := db.Conn.Query(`
+		SELECT season, episode, COALESCE(name, ''), COALESCE(air_date, ''), COALESCE(runtime, 0)
+		FROM catalog_episodes
+		WHERE tmdb_show_id = ?
+		ORDER BY season ASC, episode ASC
+	`, showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query catalog: %w", err)
+	}
+	defer rows.Close()
+
+	var episodes []model.Episode
+	for rows.Next() {
+		ep := model.Episode{TMDBShowID: showID}
+		if err := rows.Scan(&ep.Season, &ep.Episode, &ep.Name, &ep.AirDate, &ep.Runtime); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog episode: %w", err)
+		}
+		episodes = append(episodes, ep)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return episodes, nil
+}
+
+// TMDBShows returns every show matched to TMDB, by sort title. With
+// followedOnly, only shows that have been watched are returned.
+func (db *DB) TMDBShows(followedOnly bool) ([]model.Show, error) {
+	query := `
+		SELECT ` + showColumns + `
+		FROM shows s
+	`
+	if followedOnly {
+		query += ` JOIN progress p ON p.show_id = s.id`
+	}
+	query += `
+		WHERE s.tmdb_id > 0
+		ORDER BY s.sort_title, s.year
+	`
+	return db.queryShows(query)
+}
@@ -1,11 +1,120 @@
 package model
 
+import (
+	"fmt"
+	"time"
+)
+
 // here's another stupid idea, so the primary id is a hash(SHOWNAME + SEAOSN + EPISODE) and struct also has tmdb_ID now when we use tmdb enabled we will get next  episode based on tmdb id
 
 type Episode struct {
-	Id string
-	Title string
-	Season int
-	Episode int
-	Path string
+	Id      string `json:"id,omitempty"`
+	ShowID  int64  `json:"show_id,omitempty"`
+	Title   string `json:"title"`
+	Year    int    `json:"year,omitempty"` // of the show, tells remakes apart
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	Path    string `json:"path,omitempty"`
+
+	// last episode of a file that holds several (S01E01-E02), 0 otherwise
+	EpisodeEnd int `json:"episode_end,omitempty"`
+	// number counted from the first episode of the show, for files named
+	// like "[Group] Show - 137"
+	Absolute int `json:"absolute,omitempty"`
+	// what tells apart the episodes of daily shows, YYYY-MM-DD. Season and
+	// Episode are then the year and MMDD, to keep them in order
+	Date string `json:"date,omitempty"`
+
+	// how sure a scan is of the title and numbers, 0 to 1. Only set on
+	// freshly parsed episodes
+	Confidence float64 `json:"confidence,omitempty"`
+
+	// the file on disk, to recognize it after a move
+	Size int64  `json:"size,omitempty"`
+	Hash string `json:"-"` // of the start and end of the file
+
+	// metadata from TMDB, empty until the show is enriched
+	TMDBShowID int    `json:"tmdb_show_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	AirDate    string `json:"air_date,omitempty"` // YYYY-MM-DD
+	Runtime    int    `json:"runtime,omitempty"`  // minutes
+}
+
+// Code is the SxxEyy of the episode, SxxEyy-Ezz for a file holding
+// several, or the date for daily shows.
+func (e Episode) Code() string {
+	if e.Date != "" {
+		return e.Date
+	}
+	if e.EpisodeEnd > e.Episode {
+		return fmt.Sprintf("S%02dE%02d-E%02d", e.Season, e.Episode, e.EpisodeEnd)
+	}
+	return fmt.Sprintf("S%02dE%02d", e.Season, e.Episode)
+}
+
+// Show is a show of the library. Episodes and progress refer to it by ID.
+type Show struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`      // as found on disk
+	SortTitle  string `json:"sort_title"` // lowercase, without a leading article
+	Year       int    `json:"year,omitempty"`
+	TMDBID     int    `json:"tmdb_id,omitempty"`
+	FolderPath string `json:"folder_path,omitempty"`
+	Numbering  string `json:"numbering,omitempty"` // SeasonNumbering or AbsoluteNumbering, empty until set
+}
+
+// How the episodes of a show are counted, and so the order they play in.
+const (
+	SeasonNumbering   = "season"
+	AbsoluteNumbering = "absolute"
+)
+
+// Where specials (season 0) go when a show plays on, set by the specials
+// setting. Either way they can be played on their own.
+const (
+	SpecialsSkip  = "skip"  // left out
+	SpecialsAired = "aired" // between the episodes they aired between
+)
+
+// LibraryRoot is a folder the scan looks for episodes in.
+type LibraryRoot struct {
+	ID      int64    `json:"id"`
+	Path    string   `json:"path"`
+	Enabled bool     `json:"enabled"`
+	Ignore  []string `json:"ignore,omitempty"` // glob patterns, matched against names and paths relative to Path
+
+	// for removable drives and network shares: when the root is missing or
+	// empty its episodes are marked unavailable instead of removed
+	OfflineWhenUnmounted bool `json:"offline_when_unmounted"`
+}
+
+// EpisodeProgress is the resume point of a single episode, in seconds.
+type EpisodeProgress struct {
+	EpisodeID string    `json:"episode_id"`
+	Position  int       `json:"position"`
+	Duration  int       `json:"duration"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WatchEntry is one row of the watch history.
+type WatchEntry struct {
+	Episode   Episode   `json:"episode"`
+	Position  int       `json:"position"`
+	Duration  int       `json:"duration"`
+	WatchedAt time.Time `json:"watched_at"`
+}
+
+// ShowSummary is the library view of a single show.
+type ShowSummary struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	SortTitle   string    `json:"sort_title"`
+	Year        int       `json:"year,omitempty"`
+	Seasons     int       `json:"seasons"`
+	Episodes    int       `json:"episodes"`
+	LastSeason  int       `json:"last_season"`
+	LastEpisode int       `json:"last_episode"`
+	LastDate    string    `json:"last_date,omitempty"` // of the last watched episode of a daily show
+	Remaining   int       `json:"remaining"`
+	LastWatched time.Time `json:"last_watched,omitzero"` // zero if the show was never watched
 }
@@ -1,29 +1,50 @@
 package scan
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+	"unicode"
+
+	"github.com/razsteinmetz/go-ptn"
+	"github.com/yoooby/showtrack/internal/model"
 )
 
 // incase season is in the parent folder
-var folderSeasonRe = regexp.MustCompile(`(?i)season[ ._-]?(\d{1,2})`)
-// OfflineEpisodeID generates a deterministic hash for offline tracking
-func OfflineEpisodeID(title string, season, episode int) string {
-	h := sha1.New()
-	h.Write([]byte(strings.ToLower(title)))
-	h.Write([]byte{byte(season), byte(episode)})
-	return hex.EncodeToString(h.Sum(nil))
-}
-func detectSeasonFromFolder(folder string) int {
+var folderSeasonRe = regexp.MustCompile(`(?i)(?:season|series)[ ._-]?(\d{1,2})|^s(\d{1,2})$`)
+var specialsRe = regexp.MustCompile(`(?i)^specials?$`)
+
+// detectSeasonFromFolder returns the season a folder holds, with ok false
+// if it isn't a season folder. Specials are season 0.
+func detectSeasonFromFolder(folder string) (season int, ok bool) {
+	if specialsRe.MatchString(folder) {
+		return 0, true
+	}
 	match := folderSeasonRe.FindStringSubmatch(folder)
-	if match != nil && len(match) > 1 {
-		return atoi(match[1])
+	if match == nil {
+		return 0, false
 	}
-	return 0
+	return atoi(match[1] + match[2]), true
 }
 
+var (
+	// S01E02, s01.e02, 1x02
+	seasonEpisodeRe = regexp.MustCompile(`(?i)\bs(\d{1,2})[ ._-]?e(\d{1,3})|\b(\d{1,2})x(\d{2,3})\b`)
+	// E02, Ep 2, Episode.02
+	episodeOnlyRe = regexp.MustCompile(`(?i)(?:^|[ ._-])(?:e|ep|episode)[ ._-]?(\d{1,3})\b`)
+	// "02 - Title", in a season folder
+	leadingNumberRe = regexp.MustCompile(`^(\d{1,3})(?:[ ._-]|$)`)
+	// more episodes after the first one: E01E02, E01-E02, E01-02, 1x01-02
+	episodeRangeRe = regexp.MustCompile(`(?i)^(?:[ ._-]?e|-)(\d{1,3})`)
+	// daily shows, "The.Daily.Show.2025.03.14"
+	dateRe = regexp.MustCompile(`(?:^|[ ._-])(\d{4})[ ._-](\d{2})[ ._-](\d{2})(?:[ ._-]|$)`)
+	// fansub releases, "[Group] Show - 137 [1080p]", counted from the first episode
+	absoluteRe = regexp.MustCompile(`^(?:\[[^\]]*\][ ._]*)?(.+?)[ ._]+-[ ._]+(\d{1,4})(?:v\d)?(?:[ ._\[(]|$)`)
+	// the season a release folder is named after, "Show.S01.1080p"
+	titleSeasonRe = regexp.MustCompile(`(?i)[ ._-]+(?:s\d{1,2}|season[ ._-]?\d{1,2})\b.*$`)
+)
+
 func atoi(s string) int {
 	n := 0
 	for _, c := range s {
@@ -42,33 +63,209 @@ func cleanTitle(title string) string {
 	return strings.TrimSpace(title)
 }
 
+// sameTitle compares titles ignoring case, spacing and punctuation.
+func sameTitle(a, b string) bool {
+	key := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, s)
+	}
+	return key(a) != "" && key(a) == key(b)
+}
+
+// LowConfidence is the confidence below which a parse is worth a second look.
+const LowConfidence = 0.5
+
+// ParseEpisode works out which episode the video at path is, from its file
+// name and the names of the folders it sits in below root: a season folder
+// gives the season, the show folder above it the title. Confidence on the
+// result says how much of that agreed, from 0 to 1. It returns nil for
+// files that don't look like an episode.
+func ParseEpisode(path, root string) (*model.Episode, error) {
+	name := filepath.Base(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+
+	torrent, err := ptn.Parse(stem)
+	if err != nil {
+		return nil, err
+	}
+	fileTitle := cleanTitle(torrent.Title)
+	year := torrent.Year
+
+	// the file name first, SxxEyy beats a bare episode number
+	season, episode, end, absolute := -1, 0, 0, 0
+	var date time.Time
+	confidence := 0.0
+	if m := seasonEpisodeRe.FindStringSubmatchIndex(stem); m != nil {
+		if m[2] >= 0 {
+			season, episode = atoi(stem[m[2]:m[3]]), atoi(stem[m[4]:m[5]])
+		} else {
+			season, episode = atoi(stem[m[6]:m[7]]), atoi(stem[m[8]:m[9]])
+		}
+		if fileTitle == "" || torrent.IsMovie {
+			fileTitle = cleanTitle(stem[:m[0]])
+		}
+		end = episodeRangeEnd(stem[m[1]:], episode)
+		confidence += 0.6
+	} else if m, t := findDate(stem); m != nil {
+		date = t
+		fileTitle = cleanTitle(stem[:m[0]])
+		if year == t.Year() {
+			// that's the date, not the year of the show
+			year = 0
+		}
+		confidence += 0.6
+	} else if m := episodeOnlyRe.FindStringSubmatchIndex(stem); m != nil {
+		episode = atoi(stem[m[2]:m[3]])
+		fileTitle = cleanTitle(stem[:m[0]])
+		confidence += 0.3
+	} else if m := absoluteRe.FindStringSubmatch(stem); m != nil && !isYear(m[2]) {
+		absolute = atoi(m[2])
+		fileTitle = cleanTitle(m[1])
+		confidence += 0.3
+	} else if m := leadingNumberRe.FindStringSubmatch(stem); m != nil {
+		// what follows the number is the episode name
+		episode = atoi(m[1])
+		fileTitle = ""
+		confidence += 0.2
+	} else {
+		return nil, nil
+	}
+
+	// then the folders, as long as they are below root
+	folderSeason, seasonFolder := -1, false
+	var folderTitle string
+	var folderYear int
+	dir := filepath.Dir(path)
+	if underRoot(dir, root) {
+		showDir := dir
+		if s, ok := detectSeasonFromFolder(filepath.Base(dir)); ok {
+			folderSeason, seasonFolder = s, true
+			showDir = filepath.Dir(dir)
+		}
+		if underRoot(showDir, root) {
+			folder, err := ptn.Parse(filepath.Base(showDir))
+			if err == nil {
+				folderTitle = cleanTitle(titleSeasonRe.ReplaceAllString(folder.Title, ""))
+				folderYear = folder.Year
+			}
+		}
+	}
+
+	switch {
+	case !date.IsZero():
+		season, episode = date.Year(), int(date.Month())*100+date.Day()
+	case absolute > 0 && seasonFolder:
+		// "Show - 05" in a season folder is the fifth of that season
+		season, episode, absolute = folderSeason, absolute, 0
+		confidence += 0.2
+	case absolute > 0:
+		// which season it falls in is up to the catalog, until then it
+		// goes in the first one
+		season, episode = 1, absolute
+	case season >= 0 && seasonFolder && season == folderSeason:
+		confidence += 0.1
+	case season >= 0 && seasonFolder:
+		// the file name wins, but something is off
+		confidence -= 0.1
+	case season < 0 && seasonFolder:
+		season = folderSeason
+		confidence += 0.2
+	case season < 0:
+		season = 1
+	}
+
+	title := fileTitle
+	switch {
+	case fileTitle == "" && folderTitle == "":
+		return nil, nil
+	case fileTitle != "" && sameTitle(fileTitle, folderTitle):
+		// the folder is usually named by hand, keep its spelling
+		title = folderTitle
+		confidence += 0.3
+	case fileTitle == "":
+		title = folderTitle
+		confidence += 0.2
+	case folderTitle == "":
+		confidence += 0.2
+	default:
+		// a downloads folder or the like, trust the file name
+		confidence += 0.1
+	}
+	if title == folderTitle && folderYear > 0 {
+		year = folderYear
+	}
+
+	return &model.Episode{
+		Title:      title,
+		Year:       year,
+		Season:     season,
+		Episode:    episode,
+		EpisodeEnd: end,
+		Absolute:   absolute,
+		Date:       formatDate(date),
+		Path:       path,
+		Confidence: max(0, min(1, confidence)),
+	}, nil
+}
+
+// findDate returns where a valid YYYY MM DD date is in stem, and the date.
+func findDate(stem string) ([]int, time.Time) {
+	m := dateRe.FindStringSubmatchIndex(stem)
+	if m == nil {
+		return nil, time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", stem[m[2]:m[3]]+"-"+stem[m[4]:m[5]]+"-"+stem[m[6]:m[7]])
+	if err != nil {
+		return nil, time.Time{}
+	}
+	return m, t
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
 
-/* 
-func ParseEpisode(path string, folderSeason int) *model.Episode {
-    ep := &model.Episode{}
-    filename := filepath.Base(path)
-    parent := filepath.Base(filepath.Dir(path))
-	grandparent := filepath.Base(filepath.Dir(filepath.Dir(path)))
-   /*  // Detect season from parent folder only
-    season := detectSeasonFromFolder(parent)
-    if season == 0 {
-        season = folderSeason
-    }
-
-    ep.Season = season
-
-    // Parse episode number from filename
-    for _, p := range episodePatterns {
-        if match := p.FindStringSubmatch(filename); match != nil {
-            if len(match) >= 3 {
-                ep.Season = atoi(match[1])
-                ep.Episode = atoi(match[2])
-            } else if len(match) == 2 {
-                ep.Episode = atoi(match[1])
-            }
-            break
-        }
-    }
-
- */
-// TODO: DO NOT RESCAN THE WHOLE FOLDER ALL THE TIME
\ No newline at end of file
+// episodeRangeEnd returns the last episode of a range following the first
+// one in rest, or 0 if there is none.
+func episodeRangeEnd(rest string, first int) int {
+	end := 0
+	for {
+		m := episodeRangeRe.FindStringSubmatch(rest)
+		if m == nil {
+			return end
+		}
+		n := atoi(m[1])
+		rest = rest[len(m[0]):]
+		// "E01-720p" or a typo, not a range
+		if rest != "" && isAlnum(rest[0]) && rest[0] != 'e' && rest[0] != 'E' {
+			return end
+		}
+		if n <= max(first, end) || n > first+10 {
+			return end
+		}
+		end = n
+	}
+}
+
+// isYear reports whether a number is more likely a year than an episode.
+func isYear(s string) bool {
+	n := atoi(s)
+	return len(s) == 4 && n >= 1900 && n < 2100
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// underRoot reports whether dir is below root.
+func underRoot(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
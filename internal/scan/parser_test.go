@@ -0,0 +1,162 @@
+package scan
+
+import "testing"
+
+func TestParseEpisode(t *testing.T) {
+	tests := []struct {
+		path    string
+		title   string
+		year    int
+		season  int
+		episode int
+	}{
+		{"/tv/Breaking Bad/Season 1/Breaking.Bad.S01E02.720p.mkv", "Breaking Bad", 0, 1, 2},
+		{"/tv/Breaking Bad/breaking.bad.s02e05.mkv", "Breaking Bad", 0, 2, 5},
+		{"/tv/Friends/Friends 3x04.avi", "Friends", 0, 3, 4},
+		{"/tv/Lost/Season 2/Episode 07.mkv", "Lost", 0, 2, 7},
+		{"/tv/Lost/Season 3/05 - The Cost of Living.mkv", "Lost", 0, 3, 5},
+		{"/tv/Lost/S4/Lost.E03.mkv", "Lost", 0, 4, 3},
+		{"/tv/Lost/Specials/Lost.S00E01.mkv", "Lost", 0, 0, 1},
+		{"/tv/Lost/Specials/02 - Missing Pieces.mkv", "Lost", 0, 0, 2},
+		{"/tv/Doctor Who (2005)/Season 1/Doctor.Who.S01E01.mkv", "Doctor Who", 2005, 1, 1},
+		{"/tv/Show.S01.1080p/Show.S01E03.mkv", "Show", 0, 1, 3},
+		// a downloads folder, the file name knows better
+		{"/tv/Downloads/The.Wire.S01E01.mkv", "The Wire", 0, 1, 1},
+	}
+	for _, tt := range tests {
+		ep, err := ParseEpisode(tt.path, "/tv")
+		if err != nil || ep == nil {
+			t.Errorf("%s: got %v, %v", tt.path, ep, err)
+			continue
+		}
+		if ep.Title != tt.title || ep.Year != tt.year || ep.Season != tt.season || ep.Episode != tt.episode {
+			t.Errorf("%s: got %q (%d) S%02dE%02d, want %q (%d) S%02dE%02d", tt.path,
+				ep.Title, ep.Year, ep.Season, ep.Episode, tt.title, tt.year, tt.season, tt.episode)
+		}
+	}
+}
+
+func TestParseEpisodeConfidence(t *testing.T) {
+	sure, err := ParseEpisode("/tv/Lost/Season 1/Lost.S01E01.mkv", "/tv")
+	if err != nil || sure == nil {
+		t.Fatalf("got %v, %v", sure, err)
+	}
+	if sure.Confidence < 0.9 {
+		t.Errorf("folder and file name agree, yet confidence is %.2f", sure.Confidence)
+	}
+
+	// a bare number with no folder to back it up could be anything
+	unsure, err := ParseEpisode("/tv/07.mkv", "/tv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unsure != nil && unsure.Confidence >= LowConfidence {
+		t.Errorf("got confidence %.2f for %+v", unsure.Confidence, unsure)
+	}
+}
+
+func TestParseEpisodeNotAnEpisode(t *testing.T) {
+	for _, path := range []string{
+		"/tv/Movies/Inception.2010.1080p.mkv",
+		"/tv/Lost/Season 1/sample.mkv",
+	} {
+		ep, err := ParseEpisode(path, "/tv")
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+		if ep != nil {
+			t.Errorf("%s: parsed as %+v", path, ep)
+		}
+	}
+}
+
+func TestParseEpisodeRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		episode int
+		end     int
+	}{
+		{"Show.S01E01E02.mkv", 1, 2},
+		{"Show.S01E01-E03.mkv", 1, 3},
+		{"Show.S01E01-02.mkv", 1, 2},
+		{"Show 1x01-02.mkv", 1, 2},
+		// a resolution isn't the end of a range
+		{"Show.S01E01-720p.mkv", 1, 0},
+	}
+	for _, tt := range tests {
+		ep, err := ParseEpisode("/tv/Show/"+tt.name, "/tv")
+		if err != nil || ep == nil {
+			t.Errorf("%s: got %v, %v", tt.name, ep, err)
+			continue
+		}
+		if ep.Episode != tt.episode || ep.EpisodeEnd != tt.end {
+			t.Errorf("%s: got episodes %d-%d, want %d-%d", tt.name, ep.Episode, ep.EpisodeEnd, tt.episode, tt.end)
+		}
+	}
+}
+
+func TestParseEpisodeDate(t *testing.T) {
+	tests := []struct {
+		path    string
+		title   string
+		season  int
+		episode int
+		date    string
+	}{
+		{"/tv/The Daily Show/The.Daily.Show.2025.03.14.720p.mkv", "The Daily Show", 2025, 314, "2025-03-14"},
+		{"/tv/Colbert/The Late Show 2024-11-05.mkv", "The Late Show", 2024, 1105, "2024-11-05"},
+	}
+	for _, tt := range tests {
+		ep, err := ParseEpisode(tt.path, "/tv")
+		if err != nil || ep == nil {
+			t.Errorf("%s: got %v, %v", tt.path, ep, err)
+			continue
+		}
+		if ep.Title != tt.title || ep.Year != 0 || ep.Season != tt.season || ep.Episode != tt.episode || ep.Date != tt.date {
+			t.Errorf("%s: got %q (%d) S%dE%d %q, want %q S%dE%d %q", tt.path,
+				ep.Title, ep.Year, ep.Season, ep.Episode, ep.Date, tt.title, tt.season, tt.episode, tt.date)
+		}
+	}
+
+	// not a date, 13 is no month
+	ep, err := ParseEpisode("/tv/Show/Show.2025.13.01.mkv", "/tv")
+	if err == nil && ep != nil && ep.Date != "" {
+		t.Errorf("parsed date %q", ep.Date)
+	}
+}
+
+func TestParseEpisodeAbsolute(t *testing.T) {
+	tests := []struct {
+		path     string
+		season   int
+		episode  int
+		absolute int
+	}{
+		{"/tv/One Piece/[Sub] One Piece - 137 [1080p].mkv", 1, 137, 137},
+		{"/tv/Show/Season 2/Show - 05.mkv", 2, 5, 0},
+	}
+	for _, tt := range tests {
+		ep, err := ParseEpisode(tt.path, "/tv")
+		if err != nil || ep == nil {
+			t.Errorf("%s: got %v, %v", tt.path, ep, err)
+			continue
+		}
+		if ep.Season != tt.season || ep.Episode != tt.episode || ep.Absolute != tt.absolute {
+			t.Errorf("%s: got S%02dE%02d absolute %d, want S%02dE%02d absolute %d",
+				tt.path, ep.Season, ep.Episode, ep.Absolute, tt.season, tt.episode, tt.absolute)
+		}
+		if ep.Confidence < LowConfidence {
+			t.Errorf("%s: confidence %.2f is below LowConfidence", tt.path, ep.Confidence)
+		}
+	}
+}
+
+func TestParseEpisodeYearIsNotAbsolute(t *testing.T) {
+	ep, err := ParseEpisode("/tv/Show Name - 2019 [WEB].mkv", "/tv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep != nil && ep.Absolute == 2019 {
+		t.Errorf("parsed the year as absolute episode 2019: %+v", ep)
+	}
+}
@@ -0,0 +1,44 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	patterns := []string{"*sample*", "Extras", "Lost/Season 2"}
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{"/tv/Lost/lost.s01e01.sample.mkv", true}, // by name
+		{"/tv/Lost/Extras", true},
+		{"/tv/House/Extras", true},
+		{"/tv/Lost/Season 2", true}, // by path below the root
+		{"/tv/Lost/Season 1", false},
+		{"/tv/House/Season 2", false},
+		{"/tv/Lost/Lost.S01E01.mkv", false},
+	}
+	for _, tt := range tests {
+		if got := ignored("/tv", tt.path, patterns); got != tt.ignored {
+			t.Errorf("ignored(%s) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+}
+
+func TestUnmounted(t *testing.T) {
+	root := t.TempDir()
+	if !Unmounted(root) {
+		t.Error("an empty mount point counts as mounted")
+	}
+	if !Unmounted(filepath.Join(root, "gone")) {
+		t.Error("a missing folder counts as mounted")
+	}
+	if err := os.WriteFile(filepath.Join(root, "Lost.S01E01.mkv"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if Unmounted(root) {
+		t.Error("a folder with files counts as unmounted")
+	}
+}
@@ -1,47 +1,44 @@
 package scan
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"crypto/md5"
+	"crypto/sha1"
 	"encoding/hex"
-	"io/fs"
-	"sort"
-	"strconv"
 
-	"github.com/razsteinmetz/go-ptn"
 	"github.com/yoooby/showtrack/internal/db"
-	"github.com/yoooby/showtrack/internal/model"
 )
 
-// hashImmediateFiles computes a hash of all immediate files in a folder.
-func hashImmediateFiles(folder string) string {
-	entries, err := os.ReadDir(folder)
+// hashChunk is how much of the start and end of a file partialHash reads.
+const hashChunk = 64 * 1024
+
+// partialHash fingerprints a video by its size and the first and last
+// hashChunk bytes, enough to recognize it after a move without reading it all.
+func partialHash(path string, size int64) (string, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return ""
+		return "", err
 	}
+	defer f.Close()
 
-	var files []fs.DirEntry
-	for _, e := range entries {
-		if !e.IsDir() {
-			files = append(files, e)
-		}
+	h := sha1.New()
+	fmt.Fprintf(h, "%d:", size)
+	if _, err := io.CopyN(h, f, hashChunk); err != nil && err != io.EOF {
+		return "", err
 	}
-
-	// Sort filenames to get consistent hash
-	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
-
-	hash := md5.New()
-	for _, f := range files {
-		info, _ := f.Info()
-		hash.Write([]byte(f.Name()))
-		hash.Write([]byte(strconv.FormatInt(info.Size(), 10)))
-		hash.Write([]byte(info.ModTime().String()))
+	if size > 2*hashChunk {
+		if _, err := f.Seek(-hashChunk, io.SeekEnd); err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
 	}
-
-	return hex.EncodeToString(hash.Sum(nil))
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 var videoExts = map[string]bool{
@@ -52,68 +49,102 @@ var videoExts = map[string]bool{
 	".wmv": true,
 }
 
-// ScanFolder recursively scans folders, subfolders, etc.
-func ScanFolder(root string, db *db.DB) ([]model.Episode, error) {
-	var episodes []model.Episode
+// Result is what a scan found. Files that could not be read or parsed are
+// collected in Errors instead of aborting the whole scan.
+type Result struct {
+	db.ScannedTree
+	Errors []error
+}
+
+// Unmounted reports whether root looks like a drive or share that isn't
+// there: the folder is missing, or it is the empty mount point.
+func Unmounted(root string) bool {
+	entries, err := os.ReadDir(root)
+	return err != nil || len(entries) == 0
+}
+
+// ignored reports whether path matches one of the ignore patterns, by its
+// name or its path relative to root.
+func ignored(root, path string, patterns []string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, filepath.Base(path)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanFolder recursively scans folders, subfolders, etc. skipping whatever
+// matches the ignore patterns. Only the video files whose size or
+// modification time changed since the last scan are parsed; nothing is
+// saved until the result goes to db.SyncEpisodes.
+func ScanFolder(root string, ignore []string, store *db.DB) (*Result, error) {
+	res := &Result{ScannedTree: db.ScannedTree{Root: root}}
+
+	states, err := store.FileStates(root)
+	if err != nil {
+		return nil, err
+	}
 
 	// Walk recursively
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			if path == root {
+				return err
+			}
+			// keep what the library knows about anything below it
+			res.Skipped = append(res.Skipped, path)
+			res.Errors = append(res.Errors, err)
+			return nil
 		}
 
-		if info.IsDir() {
-			// Hash only immediate files in this folder
-			hash := hashImmediateFiles(path)
-
-			var oldHash string
-			err := db.Conn.QueryRow("SELECT hash FROM folder_hashes WHERE path = ?", path).Scan(&oldHash)
-			if err == nil && oldHash == hash {
-				// Folder unchanged → skip scanning files inside
+		if path != root && ignored(root, path, ignore) {
+			if info.IsDir() {
 				return filepath.SkipDir
 			}
+			return nil
+		}
 
-			// Update DB with new hash
-			_, _ = db.Conn.Exec(`
-                INSERT INTO folder_hashes(path, hash)
-                VALUES (?, ?)
-                ON CONFLICT(path) DO UPDATE SET hash=excluded.hash
-            `, path, hash)
-
+		if info.IsDir() {
 			return nil
 		}
+
 		// Skip non-video files
 		ext := strings.ToLower(filepath.Ext(info.Name()))
 		if !videoExts[ext] {
 			return nil
 		}
 
-		/*         folderSeason := 0
-		           parent := filepath.Base(filepath.Dir(path))
-		           folderSeason = detectSeasonFromFolder(parent)
-		           if folderSeason == 0 {
-		               grandparent := filepath.Base(filepath.Dir(filepath.Dir(path)))
-		               folderSeason = detectSeasonFromFolder(grandparent)
-		           } */
+		state := db.FileState{Path: path, Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		res.Files = append(res.Files, state)
+		if states[path] == state {
+			return nil
+		}
 
-		//ep := ParseEpisode(info.Name(), folderSeason)
-		torrent, err := ptn.Parse(info.Name())
+		ep, err := ParseEpisode(path, root)
 		if err != nil {
-			return err
+			res.Errors = append(res.Errors, fmt.Errorf("%s: %w", path, err))
+			return nil
 		}
-		if torrent.IsMovie {
+		if ep == nil {
+			// a movie, or nothing that looks like an episode
 			return nil
 		}
-		ep := model.Episode{
-			Id:      OfflineEpisodeID(torrent.Title, torrent.Season, torrent.Episode),
-			Title:   torrent.Title,
-			Episode: torrent.Episode,
-			Season:  torrent.Season,
-			Path:    path,
+		ep.Size = info.Size()
+
+		if ep.Hash, err = partialHash(path, ep.Size); err != nil {
+			res.Errors = append(res.Errors, fmt.Errorf("%s: %w", path, err))
 		}
-		episodes = append(episodes, ep)
+		res.Episodes = append(res.Episodes, *ep)
 		return nil
 	})
 
-	return episodes, err
+	return res, err
 }
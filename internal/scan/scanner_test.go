@@ -0,0 +1,76 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/db"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanFolderOnlyParsesChangedFiles(t *testing.T) {
+	store, err := db.InitDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	root := t.TempDir()
+	video := filepath.Join(root, "Lost", "Lost.S01E01.mkv")
+	writeFile(t, video, "video")
+	writeFile(t, filepath.Join(root, "Lost", "notes.txt"), "not a video")
+
+	rescan := func() *Result {
+		t.Helper()
+		res, err := ScanFolder(root, nil, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.SyncEpisodes(&res.ScannedTree); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := rescan(); len(res.Files) != 1 || len(res.Episodes) != 1 {
+		t.Fatalf("first scan saw %d files and parsed %d", len(res.Files), len(res.Episodes))
+	}
+	if res := rescan(); len(res.Files) != 1 || len(res.Episodes) != 0 {
+		t.Errorf("unchanged file: saw %d files and parsed %d", len(res.Files), len(res.Episodes))
+	}
+	writeFile(t, video, "a longer video")
+	if res := rescan(); len(res.Episodes) != 1 {
+		t.Errorf("changed file was not parsed again")
+	}
+}
+
+func TestScanFolderSkipsIgnored(t *testing.T) {
+	store, err := db.InitDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "Lost", "Lost.S01E01.mkv"), "video")
+	writeFile(t, filepath.Join(root, "Lost", "Lost.S01E01.sample.mkv"), "video")
+	writeFile(t, filepath.Join(root, "Lost", "Extras", "Lost.S00E01.mkv"), "video")
+
+	res, err := ScanFolder(root, []string{"*sample*", "Lost/Extras"}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 || filepath.Base(res.Files[0].Path) != "Lost.S01E01.mkv" {
+		t.Errorf("scanned %+v", res.Files)
+	}
+}
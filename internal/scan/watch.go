@@ -0,0 +1,139 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch calls sync whenever video files under root are added, changed,
+// renamed or deleted. Changes are held back until nothing under root was
+// touched for settle, so files still being downloaded aren't picked up half
+// written. It returns when ctx is cancelled, or with an error once root
+// itself can't be watched anymore, e.g. because its drive was unmounted.
+func Watch(ctx context.Context, root string, settle time.Duration, sync func()) error {
+	root = filepath.Clean(root)
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	// inotify isn't recursive, every folder gets its own watch
+	dirs := make(map[string]bool)
+	if err := watchTree(w, root, dirs); err != nil {
+		return err
+	}
+
+	interval := settle / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastChange time.Time
+	pending := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Has(fsnotify.Chmod) && !ev.Has(fsnotify.Write) {
+				continue
+			}
+
+			switch {
+			case dirs[ev.Name] && (ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename)):
+				// the kernel drops the watches, forget them too
+				for dir := range dirs {
+					if dir == ev.Name || strings.HasPrefix(dir, ev.Name+string(filepath.Separator)) {
+						delete(dirs, dir)
+					}
+				}
+				if !dirs[root] {
+					return fmt.Errorf("%s was removed", root)
+				}
+			case ev.Has(fsnotify.Create) && isDir(ev.Name):
+				// a folder moved in or created, possibly with files already in it
+				if err := watchTree(w, ev.Name, dirs); err != nil {
+					log.Printf("Failed to watch %s: %v", ev.Name, err)
+				}
+			case !videoExts[strings.ToLower(filepath.Ext(ev.Name))]:
+				continue
+			}
+			lastChange, pending = time.Now(), true
+
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// events were lost, a scan catches up with whatever happened
+				lastChange, pending = time.Now(), true
+				continue
+			}
+			log.Printf("Watch error: %v", err)
+
+		case now := <-ticker.C:
+			// an unmount drops the watches without an event
+			if !watching(w, root) {
+				return fmt.Errorf("%s is not watched anymore, it was unmounted or removed", root)
+			}
+			if pending && now.Sub(lastChange) >= settle {
+				pending = false
+				sync()
+			}
+		}
+	}
+}
+
+// watchTree adds a watch on dir and every folder below it. Each folder is
+// watched before it is listed, so one created in between isn't missed.
+func watchTree(w *fsnotify.Watcher, dir string, dirs map[string]bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			log.Printf("Failed to watch %s: %v", path, err)
+			return nil
+		}
+		if !d.IsDir() || dirs[path] {
+			return nil
+		}
+		if err := w.Add(path); err != nil {
+			log.Printf("Failed to watch %s: %v", path, err)
+			return nil
+		}
+		dirs[path] = true
+		return nil
+	})
+}
+
+// watching reports whether w still has a watch on dir.
+func watching(w *fsnotify.Watcher, dir string) bool {
+	for _, path := range w.WatchList() {
+		if path == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
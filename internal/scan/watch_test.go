@@ -0,0 +1,132 @@
+package scan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const settle = 100 * time.Millisecond
+
+// startWatch watches root and returns how many times sync was called so far.
+func startWatch(t *testing.T, root string) func() int32 {
+	t.Helper()
+	var syncs atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Watch(ctx, root, settle, func() { syncs.Add(1) }) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch: %v", err)
+		}
+	})
+	time.Sleep(50 * time.Millisecond) // for the watches to be added
+	return syncs.Load
+}
+
+// waitSyncs waits for sync to have been called want times, and a while
+// longer to catch any extra call.
+func waitSyncs(t *testing.T, syncs func() int32, want int32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for syncs() < want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(3 * settle)
+	if got := syncs(); got != want {
+		t.Fatalf("sync was called %d times, want %d", got, want)
+	}
+}
+
+func TestWatchSyncsOnceAfterSettling(t *testing.T) {
+	root := t.TempDir()
+	syncs := startWatch(t, root)
+
+	start := time.Now()
+	writeFile(t, filepath.Join(root, "Lost.S01E01.mkv"), "half")
+	time.Sleep(settle / 2)
+	writeFile(t, filepath.Join(root, "Lost.S01E01.mkv"), "half and the rest")
+	writeFile(t, filepath.Join(root, "Lost.S01E02.mkv"), "video")
+	if syncs() != 0 {
+		t.Fatal("synced while files were still being written")
+	}
+	for syncs() == 0 && time.Since(start) < 5*time.Second {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if waited := time.Since(start); waited < settle {
+		t.Errorf("synced after %s, before settling", waited)
+	}
+	waitSyncs(t, syncs, 1)
+}
+
+func TestWatchIgnoresOtherChanges(t *testing.T) {
+	root := t.TempDir()
+	video := filepath.Join(root, "Lost.S01E01.mkv")
+	writeFile(t, video, "video")
+	syncs := startWatch(t, root)
+
+	writeFile(t, filepath.Join(root, "notes.txt"), "not a video")
+	if err := os.Chmod(video, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitSyncs(t, syncs, 0)
+}
+
+func TestWatchFollowsFolders(t *testing.T) {
+	root := t.TempDir()
+	syncs := startWatch(t, root)
+
+	// a new folder is watched too
+	season := filepath.Join(root, "Lost", "Season 1")
+	if err := os.MkdirAll(season, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, filepath.Join(season, "Lost.S01E01.mkv"), "video")
+	waitSyncs(t, syncs, 1)
+
+	writeFile(t, filepath.Join(season, "Lost.S01E02.mkv"), "video")
+	waitSyncs(t, syncs, 2)
+
+	// and so is its removal
+	if err := os.RemoveAll(filepath.Join(root, "Lost")); err != nil {
+		t.Fatal(err)
+	}
+	waitSyncs(t, syncs, 3)
+}
+
+func TestWatchReturnsWhenRootIsGone(t *testing.T) {
+	for _, gone := range []struct {
+		name string
+		do   func(root string) error
+	}{
+		{"removed", os.RemoveAll},
+		{"renamed", func(root string) error { return os.Rename(root, root+".old") }},
+	} {
+		root := filepath.Join(t.TempDir(), "tv")
+		if err := os.MkdirAll(filepath.Join(root, "Lost"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- Watch(ctx, root, settle, func() {}) }()
+		time.Sleep(50 * time.Millisecond) // for the watches to be added
+
+		if err := gone.do(root); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Errorf("%s: Watch returned without an error", gone.name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: Watch kept running without its root", gone.name)
+		}
+		cancel()
+	}
+}
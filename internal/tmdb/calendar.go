@@ -0,0 +1,70 @@
+package tmdb
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// CalendarEntry is an episode that aired recently without being in the
+// library, or that airs soon.
+type CalendarEntry struct {
+	Episode model.Episode `json:"episode"`
+	Date    time.Time     `json:"date"`
+	Aired   bool          `json:"aired"`
+}
+
+// BuildCalendar lists, from the cached catalogs, the episodes of shows that
+// aired in the last recentDays but aren't in the library, and the ones
+// airing in the next upcomingDays. shows must be matched to TMDB.
+func BuildCalendar(store *db.DB, shows []model.Show, now time.Time, recentDays, upcomingDays int) ([]CalendarEntry, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from := today.AddDate(0, 0, -recentDays)
+	to := today.AddDate(0, 0, upcomingDays)
+
+	entries := []CalendarEntry{}
+	for _, show := range shows {
+		catalog, err := store.ListCatalog(show.TMDBID)
+		if err != nil {
+			return nil, err
+		}
+		have, err := store.ListEpisodes(show.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		owned := ownedEpisodes(catalog, have)
+		for _, ep := range catalog {
+			date, err := time.ParseInLocation("2006-01-02", ep.AirDate, now.Location())
+			if err != nil || date.Before(from) || date.After(to) {
+				continue
+			}
+
+			aired := !date.After(today)
+			if aired && owned[episodeKey{ep.Season, ep.Episode}] {
+				continue
+			}
+
+			ep.Title = show.Title
+			entries = append(entries, CalendarEntry{Episode: ep, Date: date, Aired: aired})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Episode.Title != b.Episode.Title {
+			return a.Episode.Title < b.Episode.Title
+		}
+		if a.Episode.Season != b.Episode.Season {
+			return a.Episode.Season < b.Episode.Season
+		}
+		return a.Episode.Episode < b.Episode.Episode
+	})
+
+	return entries, nil
+}
@@ -0,0 +1,87 @@
+package tmdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// library is a database holding have of a show, whose catalog is catalog.
+func library(t *testing.T, tmdbID int, catalog, have []model.Episode) (*db.DB, []model.Show) {
+	t.Helper()
+	store, err := db.InitDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	for i := range have {
+		have[i].Title = "Show"
+		have[i].Path = fmt.Sprintf("/tv/Show/Show.S%02dE%02d.mkv", have[i].Season, have[i].Episode)
+	}
+	if err := store.SaveEpisodes(have); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveCatalog(tmdbID, catalog); err != nil {
+		t.Fatal(err)
+	}
+	show, err := store.FindShow("Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	show.TMDBID = tmdbID
+	return store, []model.Show{*show}
+}
+
+func TestBuildCalendar(t *testing.T) {
+	// now is the 1st of June 2024
+	store, shows := library(t, 1, []model.Episode{
+		ep(1, 1, "2024-05-22"), // in the library
+		ep(1, 2, "2024-05-29"),
+		ep(1, 3, "2024-05-01"), // too long ago
+		ep(1, 4, "2024-06-01"), // today
+		ep(1, 5, "2024-06-05"),
+		ep(1, 6, "2024-07-01"), // too far ahead
+		ep(1, 7, ""),
+	}, []model.Episode{ep(1, 1, "")})
+
+	entries, err := BuildCalendar(store, shows, now, 14, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, fmt.Sprintf("S%02dE%02d %s aired:%v", e.Episode.Season, e.Episode.Episode, e.Date.Format("01-02"), e.Aired))
+	}
+	want := "S01E02 05-29 aired:true, S01E04 06-01 aired:true, S01E05 06-05 aired:false"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("got %s\nwant %s", strings.Join(got, ", "), want)
+	}
+}
+
+func TestBuildCalendarDailyShow(t *testing.T) {
+	store, shows := library(t, 1, []model.Episode{
+		ep(2024, 1, "2024-05-28"),
+		ep(2024, 2, "2024-05-29"),
+		ep(2024, 3, "2024-06-03"),
+	}, []model.Episode{
+		// numbered by date in the library
+		{Season: 2024, Episode: 528, Date: "2024-05-28"},
+	})
+
+	entries, err := BuildCalendar(store, shows, now, 14, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Date.Format("01-02"))
+	}
+	if want := "05-29, 06-03"; strings.Join(got, ", ") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, ", "), want)
+	}
+}
@@ -1 +1,196 @@
 package tmdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the TMDB v3 API. A different base URL can point the
+// client at a local stand-in.
+const DefaultBaseURL = "https://api.themoviedb.org/3"
+
+// Cache stores raw API responses so repeated lookups work offline.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, body []byte) error
+}
+
+// staleCache is implemented by caches that can return expired entries.
+type staleCache interface {
+	GetStale(key string) ([]byte, bool)
+}
+
+type Client struct {
+	BaseURL string
+	APIKey  string // v3 API key or v4 read access token
+	HTTP    *http.Client
+	Cache   Cache // optional
+}
+
+func NewClient(apiKey, baseURL string, cache Cache) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+		Cache:   cache,
+	}
+}
+
+type ShowResult struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	OriginalName string `json:"original_name"`
+	FirstAirDate string `json:"first_air_date"`
+	Overview     string `json:"overview"`
+}
+
+type SeasonSummary struct {
+	SeasonNumber int    `json:"season_number"`
+	Name         string `json:"name"`
+	EpisodeCount int    `json:"episode_count"`
+	AirDate      string `json:"air_date"`
+}
+
+type Show struct {
+	ID               int             `json:"id"`
+	Name             string          `json:"name"`
+	FirstAirDate     string          `json:"first_air_date"`
+	Status           string          `json:"status"`
+	NumberOfSeasons  int             `json:"number_of_seasons"`
+	NumberOfEpisodes int             `json:"number_of_episodes"`
+	Seasons          []SeasonSummary `json:"seasons"`
+}
+
+type Episode struct {
+	ID            int    `json:"id"`
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+	Name          string `json:"name"`
+	AirDate       string `json:"air_date"`
+	Runtime       int    `json:"runtime"`
+}
+
+type Season struct {
+	ID           int       `json:"id"`
+	SeasonNumber int       `json:"season_number"`
+	Name         string    `json:"name"`
+	AirDate      string    `json:"air_date"`
+	Episodes     []Episode `json:"episodes"`
+}
+
+// SearchShow looks up TV shows by name, best matches first.
+func (c *Client) SearchShow(query string) ([]ShowResult, error) {
+	var res struct {
+		Results []ShowResult `json:"results"`
+	}
+	if err := c.get("/search/tv", url.Values{"query": {query}}, &res); err != nil {
+		return nil, err
+	}
+	return res.Results, nil
+}
+
+func (c *Client) GetShow(id int) (*Show, error) {
+	var show Show
+	if err := c.get(fmt.Sprintf("/tv/%d", id), nil, &show); err != nil {
+		return nil, err
+	}
+	return &show, nil
+}
+
+func (c *Client) GetSeason(showID, season int) (*Season, error) {
+	var s Season
+	if err := c.get(fmt.Sprintf("/tv/%d/season/%d", showID, season), nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// get fetches path from the API, or from the cache when it has it, and
+// decodes the JSON body into v.
+func (c *Client) get(path string, query url.Values, v interface{}) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	// the key doesn't include the credentials so changing them keeps the cache
+	key := path
+	if len(query) > 0 {
+		key += "?" + query.Encode()
+	}
+
+	if c.Cache != nil {
+		if body, ok := c.Cache.Get(key); ok {
+			return json.Unmarshal(body, v)
+		}
+	}
+
+	body, err := c.fetch(path, query)
+	if err != nil {
+		// offline, an outdated answer beats none
+		if stale, ok := c.Cache.(staleCache); ok {
+			if body, ok := stale.GetStale(key); ok {
+				return json.Unmarshal(body, v)
+			}
+		}
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode TMDB response for %s: %w", path, err)
+	}
+
+	if c.Cache != nil {
+		c.Cache.Set(key, body)
+	}
+	return nil
+}
+
+func (c *Client) fetch(path string, query url.Values) ([]byte, error) {
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("no TMDB API key configured")
+	}
+
+	// v4 read access tokens are JWTs and go in a header, v3 keys in the query
+	bearer := strings.HasPrefix(c.APIKey, "eyJ")
+	if !bearer {
+		query.Set("api_key", c.APIKey)
+	}
+
+	req, err := http.NewRequest("GET", c.BaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("TMDB request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			StatusMessage string `json:"status_message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.StatusMessage != "" {
+			return nil, fmt.Errorf("TMDB %s: %s", path, apiErr.StatusMessage)
+		}
+		return nil, fmt.Errorf("TMDB %s: %s", path, resp.Status)
+	}
+
+	return body, nil
+}
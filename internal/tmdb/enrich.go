@@ -0,0 +1,188 @@
+package tmdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// EnrichResult is the outcome of enriching a single show.
+type EnrichResult struct {
+	Title      string   `json:"title"`
+	TMDBShowID int      `json:"tmdb_show_id"`
+	TMDBName   string   `json:"tmdb_name"`
+	Matched    int      `json:"matched"`
+	Unmatched  int      `json:"unmatched"`
+	Mapped     int      `json:"mapped"` // absolute numbered episodes moved to their season
+	Errors     []string `json:"errors"`
+}
+
+// MatchShow returns the TMDB id of a show, searching TMDB by title the
+// first time and remembering the match.
+func MatchShow(c *Client, store *db.DB, show *model.Show) (int, error) {
+	if show.TMDBID != 0 {
+		return show.TMDBID, nil
+	}
+
+	results, err := c.SearchShow(show.Title)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, fmt.Errorf("no TMDB show found for %q", show.Title)
+	}
+
+	// prefer an exact name match over TMDB's popularity ordering, and the
+	// right year when there are remakes
+	best := results[0]
+	bestScore := 0
+	for _, r := range results {
+		score := 0
+		if strings.EqualFold(r.Name, show.Title) || strings.EqualFold(r.OriginalName, show.Title) {
+			score += 2
+		}
+		if show.Year > 0 && strings.HasPrefix(r.FirstAirDate, strconv.Itoa(show.Year)) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+
+	if err := store.SetShowTMDBID(show.ID, best.ID); err != nil {
+		return 0, err
+	}
+	show.TMDBID = best.ID
+	return best.ID, nil
+}
+
+// Enrich stores episode names, air dates and runtimes from TMDB on every
+// episode of a show in the library.
+func Enrich(c *Client, store *db.DB, show *model.Show) (*EnrichResult, error) {
+	res := &EnrichResult{Title: show.Title, Errors: []string{}}
+
+	id, err := MatchShow(c, store, show)
+	if err != nil {
+		return nil, err
+	}
+	res.TMDBShowID = id
+
+	tmdbShow, err := c.GetShow(id)
+	if err != nil {
+		return nil, err
+	}
+	res.TMDBName = tmdbShow.Name
+
+	// absolute numbers only say which season an episode is in once the
+	// catalog is known
+	if show.Numbering == model.AbsoluteNumbering {
+		if _, err := SyncCatalog(c, store, id); err != nil {
+			return nil, err
+		}
+		var conflicts []string
+		if res.Mapped, conflicts, err = store.MapAbsoluteEpisodes(show.ID); err != nil {
+			return nil, err
+		}
+		res.Errors = append(res.Errors, conflicts...)
+	}
+
+	episodes, err := store.ListEpisodes(show.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the catalog places specials between the episodes they aired between
+	if show.Numbering != model.AbsoluteNumbering && hasSpecials(episodes) {
+		if _, err := SyncCatalog(c, store, id); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("specials: %v", err))
+		}
+	}
+
+	seasons := make(map[int]map[int]Episode)
+	var byDate map[string]model.Episode
+	var enriched []model.Episode
+	for _, ep := range episodes {
+		if ep.Date != "" {
+			// daily shows are matched by air date with the catalog
+			if byDate == nil {
+				if byDate, err = catalogByDate(c, store, id); err != nil {
+					res.Errors = append(res.Errors, fmt.Sprintf("episode list: %v", err))
+					byDate = map[string]model.Episode{}
+				}
+			}
+			meta, ok := byDate[ep.Date]
+			if !ok {
+				res.Unmatched++
+				continue
+			}
+			ep.TMDBShowID = id
+			ep.Name = meta.Name
+			ep.AirDate = meta.AirDate
+			ep.Runtime = meta.Runtime
+			enriched = append(enriched, ep)
+			res.Matched++
+			continue
+		}
+
+		catalog, ok := seasons[ep.Season]
+		if !ok {
+			catalog = make(map[int]Episode)
+			season, err := c.GetSeason(id, ep.Season)
+			if err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("season %d: %v", ep.Season, err))
+			} else {
+				for _, e := range season.Episodes {
+					catalog[e.EpisodeNumber] = e
+				}
+			}
+			seasons[ep.Season] = catalog
+		}
+
+		meta, ok := catalog[ep.Episode]
+		if !ok {
+			res.Unmatched++
+			continue
+		}
+		ep.TMDBShowID = id
+		ep.Name = meta.Name
+		ep.AirDate = meta.AirDate
+		ep.Runtime = meta.Runtime
+		enriched = append(enriched, ep)
+		res.Matched++
+	}
+
+	if err := store.SaveEpisodeMetadata(enriched); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func hasSpecials(episodes []model.Episode) bool {
+	for _, ep := range episodes {
+		if ep.Season == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// catalogByDate returns the official episode list of a show by air date.
+func catalogByDate(c *Client, store *db.DB, id int) (map[string]model.Episode, error) {
+	if _, err := SyncCatalog(c, store, id); err != nil {
+		return nil, err
+	}
+	catalog, err := store.ListCatalog(id)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]model.Episode, len(catalog))
+	for _, ep := range catalog {
+		if ep.AirDate != "" {
+			byDate[ep.AirDate] = ep
+		}
+	}
+	return byDate, nil
+}
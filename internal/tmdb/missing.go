@@ -0,0 +1,160 @@
+package tmdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+// SyncCatalog downloads the official episode list of a show, every season
+// including specials, and stores it in the catalog.
+func SyncCatalog(c *Client, store *db.DB, showID int) (*Show, error) {
+	show, err := c.GetShow(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	var catalog []model.Episode
+	for _, s := range show.Seasons {
+		season, err := c.GetSeason(showID, s.SeasonNumber)
+		if err != nil {
+			return nil, fmt.Errorf("season %d: %w", s.SeasonNumber, err)
+		}
+		for _, e := range season.Episodes {
+			catalog = append(catalog, model.Episode{
+				Title:      show.Name,
+				Season:     s.SeasonNumber,
+				Episode:    e.EpisodeNumber,
+				TMDBShowID: showID,
+				Name:       e.Name,
+				AirDate:    e.AirDate,
+				Runtime:    e.Runtime,
+			})
+		}
+	}
+
+	if err := store.SaveCatalog(showID, catalog); err != nil {
+		return nil, err
+	}
+	return show, nil
+}
+
+// MissingReport lists what the library lacks compared to the official
+// episode list. Episodes that haven't aired yet are not reported.
+type MissingReport struct {
+	Title          string          `json:"title"`
+	TMDBShowID     int             `json:"tmdb_show_id"`
+	TMDBName       string          `json:"tmdb_name"`
+	Missing        []model.Episode `json:"missing"`         // gaps in seasons we have
+	MissingSeasons []int           `json:"missing_seasons"` // seasons we have nothing of
+	Specials       []model.Episode `json:"specials"`        // season 0 episodes we don't have
+}
+
+// Empty reports whether nothing is missing.
+func (r *MissingReport) Empty() bool {
+	return len(r.Missing) == 0 && len(r.MissingSeasons) == 0 && len(r.Specials) == 0
+}
+
+// FindMissing compares the episodes of a show in the library with its
+// official episode list.
+func FindMissing(c *Client, store *db.DB, show *model.Show) (*MissingReport, error) {
+	id, err := MatchShow(c, store, show)
+	if err != nil {
+		return nil, err
+	}
+
+	tmdbShow, err := SyncCatalog(c, store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := store.ListCatalog(id)
+	if err != nil {
+		return nil, err
+	}
+	have, err := store.ListEpisodes(show.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareCatalog(show.Title, tmdbShow, catalog, have, time.Now()), nil
+}
+
+func compareCatalog(title string, show *Show, catalog, have []model.Episode, now time.Time) *MissingReport {
+	report := &MissingReport{
+		Title:          title,
+		TMDBShowID:     show.ID,
+		TMDBName:       show.Name,
+		Missing:        []model.Episode{},
+		MissingSeasons: []int{},
+		Specials:       []model.Episode{},
+	}
+
+	owned := ownedEpisodes(catalog, have)
+	ownedSeasons := make(map[int]bool)
+	for k := range owned {
+		ownedSeasons[k.season] = true
+	}
+
+	reportedSeasons := make(map[int]bool)
+	for _, ep := range catalog {
+		if !Aired(ep.AirDate, now) || owned[episodeKey{ep.Season, ep.Episode}] {
+			continue
+		}
+		ep.Title = title
+
+		switch {
+		case ep.Season == 0:
+			report.Specials = append(report.Specials, ep)
+		case !ownedSeasons[ep.Season]:
+			if !reportedSeasons[ep.Season] {
+				report.MissingSeasons = append(report.MissingSeasons, ep.Season)
+				reportedSeasons[ep.Season] = true
+			}
+		default:
+			report.Missing = append(report.Missing, ep)
+		}
+	}
+
+	return report
+}
+
+// Aired reports whether an air date (YYYY-MM-DD) is on or before now. An
+// unknown date counts as not aired.
+func Aired(airDate string, now time.Time) bool {
+	t, err := time.ParseInLocation("2006-01-02", airDate, now.Location())
+	if err != nil {
+		return false
+	}
+	return !t.After(now)
+}
+
+// episodeKey is the season and episode number of a catalog episode.
+type episodeKey struct{ season, episode int }
+
+// ownedEpisodes returns which catalog episodes the library has. Daily shows
+// are numbered by date in the library, not like the catalog, so their
+// episodes are matched by air date.
+func ownedEpisodes(catalog, have []model.Episode) map[episodeKey]bool {
+	byDate := make(map[string]episodeKey)
+	for _, ep := range catalog {
+		if ep.AirDate != "" {
+			byDate[ep.AirDate] = episodeKey{ep.Season, ep.Episode}
+		}
+	}
+
+	owned := make(map[episodeKey]bool, len(have))
+	for _, ep := range have {
+		k := episodeKey{ep.Season, ep.Episode}
+		if ep.Date != "" {
+			var ok bool
+			if k, ok = byDate[ep.Date]; !ok {
+				continue
+			}
+		}
+		owned[k] = true
+	}
+	return owned
+}
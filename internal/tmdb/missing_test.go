@@ -0,0 +1,103 @@
+package tmdb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoooby/showtrack/internal/model"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// ep is an episode that aired on date, "" for an unknown date.
+func ep(season, episode int, date string) model.Episode {
+	return model.Episode{Season: season, Episode: episode, AirDate: date}
+}
+
+func codes(eps []model.Episode) string {
+	var out []string
+	for _, e := range eps {
+		out = append(out, fmt.Sprintf("S%02dE%02d", e.Season, e.Episode))
+	}
+	return strings.Join(out, " ")
+}
+
+func TestCompareCatalog(t *testing.T) {
+	catalog := []model.Episode{
+		ep(0, 1, "2020-12-24"),
+		ep(1, 1, "2020-01-01"), ep(1, 2, "2020-01-08"), ep(1, 3, "2020-01-15"),
+		ep(2, 1, "2021-01-01"), ep(2, 2, "2021-01-08"),
+		ep(3, 1, "2024-05-31"), ep(3, 2, "2024-06-07"), ep(3, 3, ""),
+	}
+	tests := []struct {
+		name     string
+		have     []model.Episode
+		missing  string
+		seasons  string
+		specials string
+	}{
+		{"nothing", nil, "", "[1 2 3]", "S00E01"},
+		{"gap in a season", []model.Episode{ep(1, 1, ""), ep(1, 3, "")}, "S01E02", "[2 3]", "S00E01"},
+		// S03E02 airs next week, S03E03 has no date yet
+		{"up to date", []model.Episode{ep(0, 1, ""), ep(1, 1, ""), ep(1, 2, ""), ep(1, 3, ""), ep(2, 1, ""), ep(2, 2, ""), ep(3, 1, "")},
+			"", "[]", ""},
+		// S03E01 aired yesterday
+		{"new season", []model.Episode{ep(0, 1, ""), ep(1, 1, ""), ep(1, 2, ""), ep(1, 3, ""), ep(2, 1, ""), ep(2, 2, "")},
+			"", "[3]", ""},
+	}
+	for _, tt := range tests {
+		r := compareCatalog("Show", &Show{ID: 1, Name: "Show"}, catalog, tt.have, now)
+		if got := codes(r.Missing); got != tt.missing {
+			t.Errorf("%s: missing %q, want %q", tt.name, got, tt.missing)
+		}
+		if got := fmt.Sprint(r.MissingSeasons); got != tt.seasons {
+			t.Errorf("%s: missing seasons %s, want %s", tt.name, got, tt.seasons)
+		}
+		if got := codes(r.Specials); got != tt.specials {
+			t.Errorf("%s: missing specials %q, want %q", tt.name, got, tt.specials)
+		}
+		if r.Empty() != (tt.missing == "" && tt.seasons == "[]" && tt.specials == "") {
+			t.Errorf("%s: Empty is %v", tt.name, r.Empty())
+		}
+	}
+}
+
+func TestCompareCatalogDailyShow(t *testing.T) {
+	catalog := []model.Episode{
+		ep(2024, 1, "2024-05-28"), ep(2024, 2, "2024-05-29"), ep(2024, 3, "2024-05-30"),
+		ep(2024, 4, "2024-06-03"),
+	}
+	// stored by date, as year and MMDD
+	have := []model.Episode{
+		{Season: 2024, Episode: 528, Date: "2024-05-28"},
+		{Season: 2024, Episode: 530, Date: "2024-05-30"},
+		{Season: 2024, Episode: 101, Date: "2024-01-01"}, // not in the catalog
+	}
+	r := compareCatalog("Show", &Show{ID: 1, Name: "Show"}, catalog, have, now)
+	if got := codes(r.Missing); got != "S2024E02" {
+		t.Errorf("missing %q, want the episode of 05-29", got)
+	}
+	if len(r.MissingSeasons) != 0 {
+		t.Errorf("missing seasons %v", r.MissingSeasons)
+	}
+}
+
+func TestAired(t *testing.T) {
+	tests := []struct {
+		date  string
+		aired bool
+	}{
+		{"2024-05-31", true},
+		{"2024-06-01", true},
+		{"2024-06-02", false},
+		{"", false},
+		{"soon", false},
+	}
+	for _, tt := range tests {
+		if got := Aired(tt.date, now); got != tt.aired {
+			t.Errorf("Aired(%q) = %v, want %v", tt.date, got, tt.aired)
+		}
+	}
+}
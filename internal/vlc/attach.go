@@ -0,0 +1,47 @@
+package vlc
+
+import (
+	"sync"
+	"time"
+)
+
+// attachment tracks a player instance we did not spawn, so there is no
+// process to wait on. It is considered gone once it stops answering.
+type attachment struct {
+	ping     func() error
+	released chan struct{}
+	once     sync.Once
+}
+
+func newAttachment(ping func() error) *attachment {
+	return &attachment{ping: ping, released: make(chan struct{})}
+}
+
+// wait blocks until the player stops answering or release is called.
+func (a *attachment) wait() error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-a.released:
+			return nil
+		case <-ticker.C:
+			if err := a.ping(); err != nil {
+				failures++
+				// a single failure may just be a slow response
+				if failures >= 3 {
+					return err
+				}
+				continue
+			}
+			failures = 0
+		}
+	}
+}
+
+// release makes wait return without closing the player.
+func (a *attachment) release() {
+	a.once.Do(func() { close(a.released) })
+}
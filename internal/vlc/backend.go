@@ -0,0 +1,46 @@
+package vlc
+
+// MediaPlayer is a media player that Player can drive. VLC and mpv implement it.
+type MediaPlayer interface {
+	// Start launches the player process.
+	Start() error
+	// Wait blocks until the player process exits.
+	Wait() error
+	// Enqueue appends a file to the end of the playlist.
+	Enqueue(path string) error
+	// Clear empties the playlist.
+	Clear() error
+	// Play starts playing the playlist.
+	Play() error
+	// Seek jumps to an absolute position in the current item, in seconds.
+	Seek(seconds int) error
+	Status() (*Status, error)
+	// Playlist returns the items currently in the playlist, in order.
+	Playlist() ([]PlaylistItem, error)
+	// Quit closes the player.
+	Quit() error
+}
+
+// Status is the playback state reported by a MediaPlayer. Fields the player
+// did not report are left at their zero value.
+type Status struct {
+	State      string  // "playing", "paused" or "stopped"
+	Time       int     // position in the current item, in seconds
+	Length     int     // duration of the current item, in seconds
+	Position   float64 // position in the current item, from 0 to 1
+	Current    int     // id of the current playlist item, -1 when nothing is playing
+	Volume     int     // in percent
+	Rate       float64 // playback speed, 1 is normal
+	Fullscreen bool
+	Filename   string // file name of the current item
+	Title      string // title from the media metadata, if any
+}
+
+// PlaylistItem is an entry of the player playlist.
+type PlaylistItem struct {
+	ID       int
+	Name     string
+	Path     string // local path of the file
+	Duration int    // in seconds, 0 if unknown
+	Current  bool   // whether this is the item being played
+}
@@ -0,0 +1,292 @@
+package vlc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MPV drives mpv through its JSON IPC socket (--input-ipc-server).
+type MPV struct {
+	SocketPath string
+
+	cmd      *exec.Cmd
+	attached *attachment
+	mu       sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	nextID   int
+}
+
+// DefaultMPVSocket is used when no mpv_socket setting is stored.
+func DefaultMPVSocket() string {
+	return filepath.Join(os.TempDir(), "showtracker-mpv.sock")
+}
+
+type mpvRequest struct {
+	Command   []interface{} `json:"command"`
+	RequestID int           `json:"request_id"`
+}
+
+type mpvResponse struct {
+	Error     string          `json:"error"`
+	Data      json.RawMessage `json:"data"`
+	RequestID int             `json:"request_id"`
+	Event     string          `json:"event"`
+}
+
+var errPropertyUnavailable = errors.New("property unavailable")
+
+// Start reuses an mpv already listening on SocketPath, and only spawns a new
+// one when nothing answers.
+func (m *MPV) Start() error {
+	if m.connect() == nil {
+		if _, err := m.command("get_property", "idle-active"); err == nil {
+			log.Println("attached to running mpv...")
+			m.attached = newAttachment(func() error {
+				_, err := m.command("get_property", "idle-active")
+				return err
+			})
+			return nil
+		}
+	}
+
+	// a stale socket from a crashed mpv would make it fail to bind
+	os.Remove(m.SocketPath)
+
+	m.cmd = exec.Command("mpv",
+		"--idle=yes",
+		"--force-window=yes",
+		"--fullscreen",
+		"--input-ipc-server="+m.SocketPath,
+	)
+	detach(m.cmd)
+	if err := m.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start mpv: %w", err)
+	}
+	log.Println("started mpv...")
+
+	// wait for the IPC socket to come up
+	var err error
+	for i := 0; i < 50; i++ {
+		if err = m.connect(); err == nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("failed to connect to mpv: %w", err)
+}
+
+func (m *MPV) connect() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn, err := net.Dial("unix", m.SocketPath)
+	if err != nil {
+		return err
+	}
+	m.conn = conn
+	m.reader = bufio.NewReader(conn)
+	return nil
+}
+
+func (m *MPV) Wait() error {
+	if m.attached != nil {
+		return m.attached.wait()
+	}
+	if m.cmd == nil {
+		return fmt.Errorf("mpv was not started")
+	}
+	err := m.cmd.Wait()
+	os.Remove(m.SocketPath)
+	return err
+}
+
+// command sends a command over the IPC socket and returns its data, skipping
+// any events mpv interleaves with the reply.
+func (m *MPV) command(args ...interface{}) (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn == nil {
+		return nil, fmt.Errorf("not connected to mpv")
+	}
+
+	m.nextID++
+	req, err := json.Marshal(mpvRequest{Command: args, RequestID: m.nextID})
+	if err != nil {
+		return nil, err
+	}
+
+	m.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := m.conn.Write(append(req, '\n')); err != nil {
+		return nil, err
+	}
+
+	for {
+		line, err := m.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		var resp mpvResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return nil, err
+		}
+		if resp.Event != "" || resp.RequestID != m.nextID {
+			continue
+		}
+
+		switch resp.Error {
+		case "success":
+			return resp.Data, nil
+		case "property unavailable":
+			return nil, errPropertyUnavailable
+		default:
+			return nil, fmt.Errorf("mpv %v: %s", args[0], resp.Error)
+		}
+	}
+}
+
+func (m *MPV) getProperty(name string, v interface{}) error {
+	data, err := m.command("get_property", name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (m *MPV) Enqueue(path string) error {
+	_, err := m.command("loadfile", path, "append")
+	return err
+}
+
+func (m *MPV) Clear() error {
+	// stop also clears the playlist
+	_, err := m.command("stop")
+	return err
+}
+
+func (m *MPV) Play() error {
+	var idle bool
+	if err := m.getProperty("idle-active", &idle); err != nil {
+		return err
+	}
+	if idle {
+		if _, err := m.command("set_property", "playlist-pos", 0); err != nil {
+			return err
+		}
+	}
+	_, err := m.command("set_property", "pause", false)
+	return err
+}
+
+func (m *MPV) Seek(seconds int) error {
+	// seeking fails until the file is loaded
+	var err error
+	for i := 0; i < 50; i++ {
+		if _, err = m.command("seek", seconds, "absolute"); err == nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return err
+}
+
+func (m *MPV) Status() (*Status, error) {
+	status := &Status{State: "stopped", Current: -1}
+
+	var idle bool
+	if err := m.getProperty("idle-active", &idle); err != nil {
+		return nil, err
+	}
+	if idle {
+		return status, nil
+	}
+
+	var paused bool
+	if err := m.getProperty("pause", &paused); err != nil {
+		return nil, err
+	}
+	status.State = "playing"
+	if paused {
+		status.State = "paused"
+	}
+
+	if err := m.getProperty("playlist-pos", &status.Current); err != nil {
+		return nil, err
+	}
+
+	// position and duration are unavailable while a file is loading
+	var pos, duration float64
+	if err := m.getProperty("time-pos", &pos); err != nil && err != errPropertyUnavailable {
+		return nil, err
+	}
+	if err := m.getProperty("duration", &duration); err != nil && err != errPropertyUnavailable {
+		return nil, err
+	}
+	status.Time = int(pos)
+	status.Length = int(duration)
+	if duration > 0 {
+		status.Position = pos / duration
+	}
+
+	// informational only, a missing value is not an error
+	var volume float64
+	if m.getProperty("volume", &volume) == nil {
+		status.Volume = int(volume)
+	}
+	m.getProperty("speed", &status.Rate)
+	m.getProperty("fullscreen", &status.Fullscreen)
+	m.getProperty("filename", &status.Filename)
+	m.getProperty("media-title", &status.Title)
+
+	return status, nil
+}
+
+type mpvPlaylistEntry struct {
+	Filename string `json:"filename"`
+	Title    string `json:"title"`
+	Current  bool   `json:"current"`
+	Playing  bool   `json:"playing"`
+}
+
+func (m *MPV) Playlist() ([]PlaylistItem, error) {
+	var entries []mpvPlaylistEntry
+	if err := m.getProperty("playlist", &entries); err != nil {
+		return nil, err
+	}
+
+	items := make([]PlaylistItem, 0, len(entries))
+	for i, e := range entries {
+		name := e.Title
+		if name == "" {
+			name = filepath.Base(e.Filename)
+		}
+		items = append(items, PlaylistItem{
+			ID:      i, // same as playlist-pos in Status
+			Name:    name,
+			Path:    e.Filename,
+			Current: e.Current,
+		})
+	}
+	return items, nil
+}
+
+// Quit closes mpv if we spawned it. An instance we attached to is left running.
+func (m *MPV) Quit() error {
+	if m.attached != nil {
+		m.attached.release()
+		return nil
+	}
+	_, err := m.command("quit")
+	return err
+}
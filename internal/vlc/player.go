@@ -1,11 +1,9 @@
 package vlc
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
-	"os/exec"
-	"strconv"
 	"sync"
 	"time"
 
@@ -14,176 +12,354 @@ import (
 )
 
 type Player struct {
-	VLC       *VLC
+	Backend   MediaPlayer
 	CurrentEP *model.Episode
 	Queue     []*model.Episode
-	mu        sync.Mutex
-	isRunning bool
-	vlcCmd    *exec.Cmd
-	db        *db.DB
+	// PollInterval is how often the player status is checked and progress saved
+	PollInterval time.Duration
+	// SpecialsOnly plays on with the next specials instead of the show
+	SpecialsOnly bool
+	mu           sync.Mutex
+	isRunning    bool
+	exited       chan struct{} // closed when the player process exits
+	db           *db.DB
+
+	// every episode added to the playlist, by path, to map playlist items back to episodes
+	enqueued map[string]*model.Episode
+	// paths the user took out of the playlist, which are not added back
+	removed map[string]bool
+
+	// playback state of CurrentEP, used to decide when it counts as watched
+	threshold float64
+	specials  string // the specials setting
+	lastTime  int
+	lastLen   int
+	watched   bool
 }
 
-func NewPlayer(password string, port int, db db.DB) *Player {
-	vlc := VLC{
-		Host:     "127.0.0.1",
-		Port:     port,
-		Password: password,
-	}
-
+func NewPlayer(backend MediaPlayer, db db.DB) *Player {
 	return &Player{
-		VLC: &vlc,
-		db:  &db,
+		Backend:      backend,
+		PollInterval: 2 * time.Second,
+		enqueued:     make(map[string]*model.Episode),
+		removed:      make(map[string]bool),
+		db:           &db,
+		threshold:    db.CompletionThreshold(),
+		specials:     db.SpecialsPlacement(),
 	}
 }
 
-func (p *Player) PlayShow(ep model.Episode) {
+// PlayShow starts playing ep followed by the next episodes of the show and
+// tracks progress until ctx is cancelled or the player is closed. The last
+// position is saved before returning.
+func (p *Player) PlayShow(ctx context.Context, ep model.Episode) error {
 	p.mu.Lock()
-	defer p.mu.Unlock()
 
 	p.CurrentEP = &ep
 	var err error
-	p.Queue, err = p.db.GetNextEpisodes(ep.Title, ep.Season, ep.Episode, 2)
+	p.Queue, err = p.nextEpisodes(2)
 	if err != nil {
-		log.Printf("Failed to get next episodes: %v", err)
-		return
+		p.mu.Unlock()
+		return fmt.Errorf("failed to get next episodes: %w", err)
 	}
 	if !p.isRunning {
-		p.startVLC()
+		if err := p.startPlayer(); err != nil {
+			p.mu.Unlock()
+			return err
+		}
 	}
 
 	p.setupInitialQueue()
+	p.mu.Unlock()
 
-	go p.monitorPlayback()
+	return p.monitorPlayback(ctx)
 }
 
-func (p *Player) startVLC() {
-	p.vlcCmd = exec.Command("vlc",
-		"--extraintf", "http",
-		"--http-host", p.VLC.Host,
-		"--http-port", strconv.Itoa(p.VLC.Port),
-		"--http-password", p.VLC.Password,
-		"--fullscreen", // Opens in fullscreen video mode
-	)
-
-	log.Println("started VLC...")
-	if err := p.vlcCmd.Start(); err != nil {
-		log.Printf("Failed to start VLC: %v", err)
-		return
+func (p *Player) startPlayer() error {
+	if err := p.Backend.Start(); err != nil {
+		return fmt.Errorf("failed to start player: %w", err)
 	}
 
 	p.isRunning = true
+	exited := make(chan struct{})
+	p.exited = exited
 	go func() {
-		err := p.vlcCmd.Wait() // This blocks until VLC exits
-		log.Printf("VLC process exited: %v", err)
-		log.Println("VLC closed, exiting program...")
-		os.Exit(9)
+		err := p.Backend.Wait() // This blocks until the player exits
+		log.Printf("Player process exited: %v", err)
+		close(exited)
 	}()
-	time.Sleep(2 * time.Second)
+	return nil
 }
-func (p *Player) monitorPlayback() {
-	ticker := time.NewTicker(2 * time.Second)
+
+func (p *Player) monitorPlayback(ctx context.Context) error {
+	ticker := time.NewTicker(p.PollInterval)
 	defer ticker.Stop()
 
-	var lastPlaylistLength int
-	var lastCurrentPos int = -1
+	p.mu.Lock()
+	exited := p.exited
+	p.mu.Unlock()
 
-	for p.isRunning {
+	for {
 		select {
 		case <-ticker.C:
-			status, err := p.VLC.Status()
-			if err != nil {
-				log.Printf("Failed to get VLC status: %v", err)
-				continue
-			}
-			if p.CurrentEP != nil && status["state"] == "playing" {
-				currentTime := int(status["time"].(float64))
-				println("called saveprogress")
-				err := p.db.SaveProgress(p.CurrentEP.Title, p.CurrentEP.Season, p.CurrentEP.Episode, currentTime)
-				if err != nil {
-					fmt.Println("Error", err.Error())
-				}
-			}
-
-			currentPos := int(status["currentplid"].(float64))
-			playlistLength := int(status["length"].(float64))
+			p.poll()
+		case <-exited:
+			// nothing left to poll, keep what the last poll saw
+			log.Println("Player closed, saving progress...")
+			p.mu.Lock()
+			p.isRunning = false
+			p.recordIfWatched()
+			p.mu.Unlock()
+			return nil
+		case <-ctx.Done():
+			log.Println("Shutting down, saving progress...")
+			p.poll()
+			p.Stop()
+			return nil
+		}
+	}
+}
 
-			if currentPos != lastCurrentPos && lastCurrentPos != -1 {
-				p.onEpisodeFinished()
-				log.Printf("Episode changed: position %d -> %d", lastCurrentPos, currentPos)
-			}
+// poll reads the player status, saves progress and tops up the queue.
+func (p *Player) poll() {
+	status, err := p.Backend.Status()
+	if err != nil {
+		log.Printf("Failed to get player status: %v", err)
+		return
+	}
+	p.reconcilePlaylist(status)
+	p.saveStatus(status)
+	p.maintainQueue()
+}
 
-			if playlistLength < lastPlaylistLength {
-				p.onEpisodeFinished()
-				log.Printf("Playlist shortened: %d -> %d", lastPlaylistLength, playlistLength)
-			}
+func (p *Player) saveStatus(status *Status) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-			lastCurrentPos = currentPos
-			lastPlaylistLength = playlistLength
+	if p.CurrentEP == nil || status.State == "stopped" {
+		return
+	}
 
-			p.maintainQueue(playlistLength)
+	currentTime := status.Time
+	duration := status.Length
+	if p.tracksProgress() {
+		err := p.db.SaveProgress(p.CurrentEP.ShowID, p.CurrentEP.Season, p.progressEpisode(), currentTime)
+		if err != nil {
+			log.Printf("Failed to save progress: %v", err)
 		}
 	}
+	if err := p.db.SaveEpisodeProgress(p.CurrentEP.Id, currentTime, duration); err != nil {
+		log.Printf("Failed to save episode progress: %v", err)
+	}
+	p.lastTime, p.lastLen = currentTime, duration
+	p.recordIfWatched()
+}
+
+// progressEpisode is the episode the show progress points at while
+// CurrentEP plays: the last one of a multi-episode file once it is watched.
+// Callers must hold p.mu.
+func (p *Player) progressEpisode() int {
+	if p.watched && p.CurrentEP.EpisodeEnd > p.CurrentEP.Episode {
+		return p.CurrentEP.EpisodeEnd
+	}
+	return p.CurrentEP.Episode
+}
+
+// tracksProgress reports whether playing CurrentEP moves the show on. A
+// special that the show doesn't play on to leaves it where it was. Callers
+// must hold p.mu.
+func (p *Player) tracksProgress() bool {
+	return p.CurrentEP.Season > 0 || (p.specials == model.SpecialsAired && !p.SpecialsOnly)
+}
+
+// nextEpisodes returns up to count episodes to play after CurrentEP.
+// Callers must hold p.mu.
+func (p *Player) nextEpisodes(count int) ([]*model.Episode, error) {
+	if p.SpecialsOnly && p.CurrentEP.Season == 0 {
+		return p.db.GetNextSpecials(p.CurrentEP.ShowID, p.CurrentEP.Episode, count)
+	}
+	return p.db.GetNextEpisodes(p.CurrentEP.ShowID, p.CurrentEP.Season, p.CurrentEP.Episode, count)
 }
 
 func (p *Player) setupInitialQueue() {
-	if err := p.VLC.ClearPlaylist(); err != nil {
+	if err := p.Backend.Clear(); err != nil {
 		log.Printf("Failed to clear playlist: %v", err)
 		return
 	}
+	p.enqueued = make(map[string]*model.Episode)
+	p.removed = make(map[string]bool)
 
 	if p.CurrentEP != nil {
-		progress, err := p.db.GetProgress(p.CurrentEP.Title)
+		progress, err := p.db.GetEpisodeProgress(p.CurrentEP.Id)
 		if err != nil {
 			log.Printf("Failed to get progress: %v", err)
 			return
 		}
-		if err := p.VLC.AddToPlaylist(p.CurrentEP.Path); err != nil {
+		if err := p.enqueue(p.CurrentEP); err != nil {
 			log.Printf("Failed to add current episode: %v", err)
 			return
 		}
 
-		p.VLC.Play()
-		if progress > 0 {
-			p.VLC.Seek(progress)
+		p.Backend.Play()
+		if progress.Position > 0 && !p.isComplete(progress.Position, progress.Duration) {
+			p.Backend.Seek(progress.Position)
 		}
 	}
 
 	for _, ep := range p.Queue {
-		if err := p.VLC.AddToPlaylist(ep.Path); err != nil {
+		if err := p.enqueue(ep); err != nil {
 			log.Printf("Failed to add episode to playlist: %v", err)
 		}
 	}
 }
 
-func (p *Player) onEpisodeFinished() {
+// enqueue adds an episode to the player playlist. Callers must hold p.mu.
+func (p *Player) enqueue(ep *model.Episode) error {
+	if err := p.Backend.Enqueue(ep.Path); err != nil {
+		return err
+	}
+	p.enqueued[pathKey(ep.Path)] = ep
+	return nil
+}
+
+// reconcilePlaylist maps the item being played back to its episode, so
+// CurrentEP and Queue follow whatever the user does inside the player
+// (skipping, going back, reordering or removing items).
+func (p *Player) reconcilePlaylist(status *Status) {
+	if status.State == "stopped" {
+		return
+	}
+
+	items, err := p.Backend.Playlist()
+	if err != nil {
+		log.Printf("Failed to get playlist: %v", err)
+		return
+	}
+
+	// the item status was read for, so its time isn't credited to the
+	// next one when playback moved on in between
+	current := -1
+	for i, item := range items {
+		if item.ID == status.Current {
+			current = i
+			break
+		}
+	}
+	if current == -1 {
+		for i, item := range items {
+			if item.Current {
+				current = i
+				break
+			}
+		}
+	}
+	if current == -1 {
+		return
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if len(p.Queue) > 0 {
-		p.CurrentEP = p.Queue[0]
-		p.Queue = p.Queue[1:]
-	} else {
-		p.CurrentEP = nil
+	present := make(map[string]bool, len(items))
+	for _, item := range items {
+		present[pathKey(item.Path)] = true
+	}
+	for key := range p.enqueued {
+		if !present[key] {
+			p.removed[key] = true
+			delete(p.enqueued, key)
+		}
 	}
+
+	ep := p.enqueued[pathKey(items[current].Path)]
+	if ep == nil {
+		if p.CurrentEP != nil {
+			log.Printf("Playing %s which is not in the library, progress is not tracked", items[current].Name)
+		}
+		p.onEpisodeChanged(nil)
+	} else if p.CurrentEP == nil || p.CurrentEP.Id != ep.Id {
+		log.Printf("Episode changed: %s %s", ep.Title, ep.Code())
+		p.onEpisodeChanged(ep)
+	}
+
+	// whatever follows the current item is the queue
+	p.Queue = p.Queue[:0]
+	for _, item := range items[current+1:] {
+		if queued := p.enqueued[pathKey(item.Path)]; queued != nil && !p.isInQueue(queued) {
+			p.Queue = append(p.Queue, queued)
+		}
+	}
+}
+
+// onEpisodeChanged records the episode that was playing before switching to
+// ep. Callers must hold p.mu.
+func (p *Player) onEpisodeChanged(ep *model.Episode) {
+	p.recordIfWatched()
+	p.lastTime, p.lastLen, p.watched = 0, 0, false
+	p.CurrentEP = ep
 }
 
-func (p *Player) maintainQueue(currentPlaylistLength int) {
+func (p *Player) isComplete(position, duration int) bool {
+	return duration > 0 && float64(position) >= float64(duration)*p.threshold
+}
+
+// recordIfWatched adds CurrentEP to the watch history once it passed the
+// completion threshold, with every other episode its file holds. Callers
+// must hold p.mu.
+func (p *Player) recordIfWatched() {
+	if p.CurrentEP == nil || p.watched || !p.isComplete(p.lastTime, p.lastLen) {
+		return
+	}
+	if err := p.db.RecordWatch(p.CurrentEP.Id, p.lastTime, p.lastLen); err != nil {
+		log.Printf("Failed to record watch: %v", err)
+		return
+	}
+	p.watched = true
+
+	ep := p.CurrentEP
+	if ep.EpisodeEnd <= ep.Episode {
+		return
+	}
+	for n := ep.Episode + 1; n <= ep.EpisodeEnd; n++ {
+		other, err := p.db.GetEpisode(ep.ShowID, ep.Season, n)
+		if err != nil {
+			log.Printf("Failed to record watch of S%02dE%02d: %v", ep.Season, n, err)
+			continue
+		}
+		if err := p.db.RecordWatch(other.Id, p.lastTime, p.lastLen); err != nil {
+			log.Printf("Failed to record watch: %v", err)
+		}
+	}
+	// so the show continues after the whole file
+	if !p.tracksProgress() {
+		return
+	}
+	if err := p.db.SaveProgress(ep.ShowID, ep.Season, ep.EpisodeEnd, p.lastTime); err != nil {
+		log.Printf("Failed to save progress: %v", err)
+	}
+}
+
+func (p *Player) maintainQueue() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	if len(p.Queue) < 3 && p.CurrentEP != nil {
-		moreEpisodes, err := p.db.GetNextEpisodes(p.CurrentEP.Title, p.CurrentEP.Season, p.CurrentEP.Episode, 5-len(p.Queue))
+		// removed episodes come back from the library, get enough to skip them
+		moreEpisodes, err := p.nextEpisodes(5 - len(p.Queue) + len(p.removed))
 		if err != nil {
 			log.Printf("Failed to get next episodes: %v", err)
 			return
 		}
 
 		for _, ep := range moreEpisodes {
-			if !p.isInQueue(ep) {
+			if len(p.Queue) >= 5 {
+				break
+			}
+			if !p.isInQueue(ep) && !p.removed[pathKey(ep.Path)] {
 				p.Queue = append(p.Queue, ep)
 
-				if err := p.VLC.AddToPlaylist(ep.Path); err != nil {
-					log.Printf("Failed to add episode to VLC playlist: %v", err)
+				if err := p.enqueue(ep); err != nil {
+					log.Printf("Failed to add episode to playlist: %v", err)
 				}
 			}
 		}
@@ -192,19 +368,32 @@ func (p *Player) maintainQueue(currentPlaylistLength int) {
 
 func (p *Player) isInQueue(ep *model.Episode) bool {
 	for _, queueEp := range p.Queue {
-		if queueEp.Title == ep.Title && queueEp.Season == ep.Season && queueEp.Episode == ep.Episode {
+		if queueEp.ShowID == ep.ShowID && queueEp.Season == ep.Season && queueEp.Episode == ep.Episode {
 			return true
 		}
 	}
 	return false
 }
 
+// Stop closes the player and waits for it to exit.
 func (p *Player) Stop() {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
+	if !p.isRunning {
+		p.mu.Unlock()
+		return
+	}
 	p.isRunning = false
-	if p.vlcCmd != nil && p.vlcCmd.Process != nil {
-		p.vlcCmd.Process.Kill()
+	exited := p.exited
+	// polling and saving go on while it exits
+	p.mu.Unlock()
+
+	if err := p.Backend.Quit(); err != nil {
+		log.Printf("Failed to quit player: %v", err)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		log.Println("Player did not exit in time")
 	}
 }
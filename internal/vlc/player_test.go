@@ -0,0 +1,317 @@
+package vlc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yoooby/showtrack/internal/db"
+	"github.com/yoooby/showtrack/internal/model"
+	"github.com/yoooby/showtrack/internal/vlc/vlctest"
+)
+
+// length is how long every episode of the test library is, in seconds.
+const length = vlctest.DefaultLength
+
+// fakeProcess is a VLC whose process is the test server: it is never
+// spawned, and exits when quit.
+type fakeProcess struct {
+	*VLC
+	exited chan struct{}
+	once   sync.Once
+}
+
+func (f *fakeProcess) Start() error { return nil }
+func (f *fakeProcess) Wait() error  { <-f.exited; return nil }
+func (f *fakeProcess) Quit() error  { f.once.Do(func() { close(f.exited) }); return nil }
+
+// playerTest is a library of one show and a fake VLC to play it in.
+type playerTest struct {
+	t   *testing.T
+	db  *db.DB
+	srv *vlctest.Server
+	eps []*model.Episode
+}
+
+func newPlayerTest(t *testing.T, episodes int) *playerTest {
+	var files []model.Episode
+	for n := 1; n <= episodes; n++ {
+		files = append(files, model.Episode{Title: "Show", Season: 1, Episode: n,
+			Path: fmt.Sprintf("/tv/Show/Show.S01E%02d.mkv", n)})
+	}
+	return newPlayerTestOf(t, files)
+}
+
+// newPlayerTestOf is a library of the season 1 files given.
+func newPlayerTestOf(t *testing.T, files []model.Episode) *playerTest {
+	t.Helper()
+	store, err := db.InitDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	srv := vlctest.NewServer("secret")
+	t.Cleanup(srv.Close)
+
+	if err := store.SaveEpisodes(files); err != nil {
+		t.Fatal(err)
+	}
+	show, err := store.FindShow("Show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := &playerTest{t: t, db: store, srv: srv}
+	for n := 1; ; n++ {
+		ep, err := store.GetEpisode(show.ID, 1, n)
+		if err != nil {
+			break
+		}
+		pt.eps = append(pt.eps, ep)
+	}
+	return pt
+}
+
+// play starts playing episode n and returns a function that stops the
+// player and waits for PlayShow to return.
+func (pt *playerTest) play(n int) func() {
+	stop, _ := pt.playOn(&fakeProcess{VLC: pt.vlc(), exited: make(chan struct{})}, n)
+	return stop
+}
+
+func (pt *playerTest) vlc() *VLC {
+	return &VLC{Host: pt.srv.Host(), Port: pt.srv.Port(), Password: "secret"}
+}
+
+// playOn is play with another backend, it also returns what PlayShow returned.
+func (pt *playerTest) playOn(backend MediaPlayer, n int) (func(), <-chan error) {
+	p := NewPlayer(backend, *pt.db)
+	p.PollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	returned := make(chan error, 1)
+	go func() {
+		err := p.PlayShow(ctx, *pt.eps[n-1])
+		done <- err
+		returned <- err
+	}()
+	return func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				pt.t.Errorf("PlayShow: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			pt.t.Fatal("PlayShow did not return")
+		}
+	}, returned
+}
+
+var episodeRe = regexp.MustCompile(`E(\d+)\.mkv$`)
+
+// playlist returns the episode numbers in the player playlist.
+func (pt *playerTest) playlist() []int {
+	var out []int
+	for _, item := range pt.srv.Playlist() {
+		uri, _ := url.PathUnescape(item.URI)
+		n := 0
+		if m := episodeRe.FindStringSubmatch(uri); m != nil {
+			n, _ = strconv.Atoi(m[1])
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// item returns the playlist item of episode n.
+func (pt *playerTest) item(n int) vlctest.Item {
+	pt.t.Helper()
+	for i, e := range pt.playlist() {
+		if e == n {
+			return pt.srv.Playlist()[i]
+		}
+	}
+	pt.t.Fatalf("E%02d is not in the playlist %v", n, pt.playlist())
+	return vlctest.Item{}
+}
+
+func (pt *playerTest) watched(n int) bool {
+	watched, err := pt.db.IsWatched(pt.eps[n-1].Id)
+	if err != nil {
+		pt.t.Fatal(err)
+	}
+	return watched
+}
+
+// progress returns the episode the show progress is on.
+func (pt *playerTest) progress() int {
+	shows, err := pt.db.ListShows("")
+	if err != nil || len(shows) != 1 {
+		pt.t.Fatalf("ListShows: %v, %v", shows, err)
+	}
+	return shows[0].LastEpisode
+}
+
+// eventually waits for cond, which the player gets to on its next polls.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPlayerPlaysOnAndSavesProgress(t *testing.T) {
+	pt := newPlayerTest(t, 3)
+	stop := pt.play(1)
+	defer stop()
+
+	eventually(t, "the queue", func() bool { return len(pt.srv.Playlist()) == 3 })
+	if got := fmt.Sprint(pt.playlist()); got != "[1 2 3]" {
+		t.Errorf("playlist is %s", got)
+	}
+
+	pt.srv.Advance(length * 95 / 100 * time.Second)
+	eventually(t, "E01 to be watched", func() bool { return pt.watched(1) })
+
+	// ten seconds into the next episode
+	pt.srv.Advance((length - length*95/100 + 10) * time.Second)
+	eventually(t, "progress on E02", func() bool { return pt.progress() == 2 })
+	eventually(t, "E02 to resume at 10s", func() bool {
+		resume, err := pt.db.GetEpisodeProgress(pt.eps[1].Id)
+		return err == nil && resume.Position == 10 && resume.Duration == length
+	})
+	if pt.watched(2) {
+		t.Error("E02 counts as watched after 10 seconds")
+	}
+}
+
+func TestPlayerResumesEpisode(t *testing.T) {
+	pt := newPlayerTest(t, 2)
+	if err := pt.db.SaveEpisodeProgress(pt.eps[0].Id, 40, length); err != nil {
+		t.Fatal(err)
+	}
+	stop := pt.play(1)
+	defer stop()
+
+	eventually(t, "the seek", func() bool { return pt.srv.Time() == 40 })
+}
+
+func TestPlayerStartsOverWatchedEpisode(t *testing.T) {
+	pt := newPlayerTest(t, 2)
+	if err := pt.db.SaveEpisodeProgress(pt.eps[0].Id, length-10, length); err != nil {
+		t.Fatal(err)
+	}
+	stop := pt.play(1)
+	defer stop()
+
+	eventually(t, "the playlist", func() bool { return len(pt.srv.Playlist()) == 2 })
+	time.Sleep(50 * time.Millisecond) // a few polls
+	if pt.srv.Time() != 0 {
+		t.Errorf("resumed a finished episode at %d", pt.srv.Time())
+	}
+}
+
+func TestPlayerFollowsSkips(t *testing.T) {
+	pt := newPlayerTest(t, 3)
+	stop := pt.play(1)
+	defer stop()
+
+	eventually(t, "the queue", func() bool { return len(pt.srv.Playlist()) == 3 })
+	pt.srv.PlayID(pt.item(3).ID)
+	eventually(t, "progress on E03", func() bool { return pt.progress() == 3 })
+	if pt.watched(1) {
+		t.Error("skipped E01 counts as watched")
+	}
+
+	// and back
+	pt.srv.PlayID(pt.item(1).ID)
+	eventually(t, "progress on E01", func() bool { return pt.progress() == 1 })
+}
+
+func TestPlayerSavesPositionOnStop(t *testing.T) {
+	pt := newPlayerTest(t, 2)
+	stop := pt.play(1)
+	eventually(t, "playback", func() bool { return pt.srv.State() == "playing" })
+
+	pt.srv.Advance(30 * time.Second)
+	stop()
+	resume, err := pt.db.GetEpisodeProgress(pt.eps[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resume.Position != 30 {
+		t.Errorf("E01 resumes at %d, want 30", resume.Position)
+	}
+}
+
+func TestPlayShowReturnsWhenPlayerCloses(t *testing.T) {
+	pt := newPlayerTest(t, 2)
+	backend := &fakeProcess{VLC: pt.vlc(), exited: make(chan struct{})}
+	_, returned := pt.playOn(backend, 1)
+	eventually(t, "playback", func() bool { return pt.srv.State() == "playing" })
+
+	pt.srv.Advance(length * 95 / 100 * time.Second)
+	time.Sleep(50 * time.Millisecond) // a few polls
+	backend.Quit()                    // the user closed it
+	select {
+	case err := <-returned:
+		if err != nil {
+			t.Errorf("PlayShow: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PlayShow did not return after the player closed")
+	}
+	if !pt.watched(1) {
+		t.Error("E01 was not recorded after the player closed")
+	}
+}
+
+func TestPlayerRecordsWholeFile(t *testing.T) {
+	pt := newPlayerTestOf(t, []model.Episode{
+		{Title: "Show", Season: 1, Episode: 1, EpisodeEnd: 2, Path: "/tv/Show/Show.S01E01E02.mkv"},
+		{Title: "Show", Season: 1, Episode: 3, Path: "/tv/Show/Show.S01E03.mkv"},
+	})
+	stop := pt.play(1)
+	defer stop()
+
+	// the file plays once, for both its episodes
+	eventually(t, "the queue", func() bool { return len(pt.srv.Playlist()) == 2 })
+	time.Sleep(50 * time.Millisecond) // a few polls
+	if got := fmt.Sprint(pt.playlist()); got != "[2 3]" {
+		t.Errorf("playlist is %s, want the two files", got)
+	}
+
+	pt.srv.Advance(length * 95 / 100 * time.Second)
+	eventually(t, "E01 and E02 to be watched", func() bool { return pt.watched(1) && pt.watched(2) })
+	eventually(t, "progress on E02", func() bool { return pt.progress() == 2 })
+}
+
+func TestPlayerDoesNotEnqueueRemovedEpisodes(t *testing.T) {
+	pt := newPlayerTest(t, 8)
+	stop := pt.play(1)
+	defer stop()
+
+	eventually(t, "the queue", func() bool { return len(pt.srv.Playlist()) == 4 })
+	for n := 3; n <= 4; n++ {
+		pt.srv.Remove(pt.item(n).ID)
+	}
+
+	// the queue is topped up past the removed episodes
+	eventually(t, "E05 to E07", func() bool { return fmt.Sprint(pt.playlist()) == "[1 2 5 6 7]" })
+	time.Sleep(50 * time.Millisecond) // a few more polls
+	if got := fmt.Sprint(pt.playlist()); got != "[1 2 5 6 7]" {
+		t.Errorf("playlist is %s, want [1 2 5 6 7]", got)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package vlc
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach puts the player in its own process group, so a Ctrl+C in the
+// terminal reaches showtracker only and it can save the final position
+// before closing the player itself.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,91 @@
+package vlc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// vlcStatus is the body of /requests/status.json. VLC leaves out fields when
+// idle and the types of some of them changed between versions, so numbers
+// and booleans are decoded leniently.
+type vlcStatus struct {
+	State       string     `json:"state"`
+	Time        flexNumber `json:"time"`
+	Length      flexNumber `json:"length"`
+	Position    flexNumber `json:"position"`
+	CurrentPlID *int       `json:"currentplid"`
+	Volume      flexNumber `json:"volume"`
+	Rate        flexNumber `json:"rate"`
+	Fullscreen  flexBool   `json:"fullscreen"`
+	Information struct {
+		Category struct {
+			Meta struct {
+				Filename string `json:"filename"`
+				Title    string `json:"title"`
+			} `json:"meta"`
+		} `json:"category"`
+	} `json:"information"`
+}
+
+func (s *vlcStatus) toStatus() *Status {
+	status := &Status{
+		State:      s.State,
+		Time:       int(s.Time),
+		Length:     int(s.Length),
+		Position:   float64(s.Position),
+		Current:    -1,
+		Volume:     int(float64(s.Volume) * 100 / 256), // VLC volume goes from 0 to 512, 256 being 100%
+		Rate:       float64(s.Rate),
+		Fullscreen: bool(s.Fullscreen),
+		Filename:   s.Information.Category.Meta.Filename,
+		Title:      s.Information.Category.Meta.Title,
+	}
+	if status.State == "" {
+		status.State = "stopped"
+	}
+	if s.CurrentPlID != nil {
+		status.Current = *s.CurrentPlID
+	}
+	return status
+}
+
+// flexNumber accepts a JSON number, a numeric string or null.
+type flexNumber float64
+
+func (n *flexNumber) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if len(data) == 0 || string(data) == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		// not worth failing the whole status for
+		*n = 0
+		return nil
+	}
+	*n = flexNumber(f)
+	return nil
+}
+
+// flexBool accepts a JSON boolean or a number, VLC reports fullscreen as either.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = flexBool(v)
+	case float64:
+		*b = v != 0
+	case string:
+		*b = v == "true" || v == "1"
+	default:
+		*b = false
+	}
+	return nil
+}
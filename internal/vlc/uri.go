@@ -0,0 +1,41 @@
+package vlc
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// fileURI turns a local path into a file:// URI.
+func fileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// pathFromURI turns a file:// URI back into a local path. Anything else is
+// returned unchanged.
+func pathFromURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // windows drive letter
+	}
+	return filepath.FromSlash(path)
+}
+
+// pathKey normalizes a path so the same file always gives the same key.
+func pathKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
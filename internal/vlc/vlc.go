@@ -2,114 +2,220 @@ package vlc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"os/exec"
+	"strconv"
 	"time"
 )
 
+// VLC drives VLC through its HTTP interface.
 type VLC struct {
 	Host     string
 	Port     int
 	Password string
+
+	cmd *exec.Cmd
+	// set when we attached to a VLC that was already running instead of spawning one
+	attached *attachment
 }
 
-func (v *VLC) Status() (map[string]interface{}, error) {
-	url := fmt.Sprintf("http://%s:%d/requests/status.json", v.Host, v.Port)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.SetBasicAuth("", v.Password)
+// Start reuses a VLC already listening on Host:Port, and only spawns a new
+// one when nothing answers. A VLC that answers with an error, like a wrong
+// password, is reported since another one couldn't take its port.
+func (v *VLC) Start() error {
+	_, err := v.Status()
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		if httpErr.code == http.StatusUnauthorized {
+			return fmt.Errorf("VLC is already running on port %d but rejected the password, check vlc_password: %w", v.Port, err)
+		}
+		return fmt.Errorf("VLC is already running on port %d but can't be used: %w", v.Port, err)
+	}
+	if err == nil {
+		log.Println("attached to running VLC...")
+		v.attached = newAttachment(func() error {
+			_, err := v.Status()
+			return err
+		})
+		return nil
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	v.cmd = exec.Command("vlc",
+		"--extraintf", "http",
+		"--http-host", v.Host,
+		"--http-port", strconv.Itoa(v.Port),
+		"--http-password", v.Password,
+		"--fullscreen", // Opens in fullscreen video mode
+	)
+
+	detach(v.cmd)
+	if err := v.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start VLC: %w", err)
 	}
-	defer resp.Body.Close()
+	log.Println("started VLC...")
 
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+	// give the HTTP interface time to come up
+	time.Sleep(2 * time.Second)
+	return nil
+}
+
+func (v *VLC) Wait() error {
+	if v.attached != nil {
+		return v.attached.wait()
+	}
+	if v.cmd == nil {
+		return fmt.Errorf("VLC was not started")
 	}
+	return v.cmd.Wait()
+}
 
-	return data, nil
+// Quit closes VLC if we spawned it. An instance we attached to is left running.
+func (v *VLC) Quit() error {
+	if v.attached != nil {
+		v.attached.release()
+		return nil
+	}
+	if v.cmd == nil || v.cmd.Process == nil {
+		return nil
+	}
+	return v.cmd.Process.Kill()
 }
-func (v *VLC) AddToPlaylist(path string) error {
-	fileURL := "file://" + url.PathEscape(path)
-	url := fmt.Sprintf("http://%s:%d/requests/status.xml?command=in_enqueue&input=%s",
-		v.Host, v.Port, fileURL)
 
-	req, err := http.NewRequest("GET", url, nil)
+// get performs an authenticated GET against the HTTP interface.
+func (v *VLC) get(path string, query url.Values) (*http.Response, error) {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     net.JoinHostPort(v.Host, strconv.Itoa(v.Port)),
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.SetBasicAuth("", v.Password)
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &httpError{path: path, status: resp.Status, code: resp.StatusCode}
+	}
+	return resp, nil
+}
 
-	return nil
+// httpError is a response from VLC other than 200 OK.
+type httpError struct {
+	path   string
+	status string
+	code   int
 }
 
-func (v *VLC) ClearPlaylist() error {
-	url := fmt.Sprintf("http://%s:%d/requests/status.xml?command=pl_empty",
-		v.Host, v.Port)
+func (e *httpError) Error() string {
+	return fmt.Sprintf("VLC request %s failed: %s", e.path, e.status)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// command runs a playlist command through status.xml.
+func (v *VLC) command(query url.Values) error {
+	resp, err := v.get("/requests/status.xml", query)
 	if err != nil {
 		return err
 	}
-	req.SetBasicAuth("", v.Password)
+	resp.Body.Close()
+	return nil
+}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+func (v *VLC) Status() (*Status, error) {
+	resp, err := v.get("/requests/status.json", nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	return nil
+	var data vlcStatus
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode VLC status: %w", err)
+	}
+
+	return data.toStatus(), nil
 }
 
-func (v *VLC) Play() error {
-	url := fmt.Sprintf("http://%s:%d/requests/status.xml?command=pl_play",
-		v.Host, v.Port)
+// playlistNode is a node of /requests/playlist.json. Ids are sent as strings.
+type playlistNode struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	URI      string         `json:"uri"`
+	Duration flexNumber     `json:"duration"`
+	Current  string         `json:"current"`
+	Children []playlistNode `json:"children"`
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// Playlist returns the items of the VLC playlist, ignoring the media library.
+func (v *VLC) Playlist() ([]PlaylistItem, error) {
+	resp, err := v.get("/requests/playlist.json", nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	req.SetBasicAuth("", v.Password)
+	defer resp.Body.Close()
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	var root playlistNode
+	if err := json.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return nil, fmt.Errorf("failed to decode VLC playlist: %w", err)
 	}
-	defer resp.Body.Close()
 
-	return nil
+	// the root has the playlist and the media library as children
+	node := root
+	for _, child := range root.Children {
+		if child.ID == "1" || child.Name == "Playlist" {
+			node = child
+			break
+		}
+	}
+
+	var items []PlaylistItem
+	var walk func(n playlistNode)
+	walk = func(n playlistNode) {
+		if n.Type == "leaf" {
+			id, _ := strconv.Atoi(n.ID)
+			items = append(items, PlaylistItem{
+				ID:       id,
+				Name:     n.Name,
+				Path:     pathFromURI(n.URI),
+				Duration: int(n.Duration),
+				Current:  n.Current == "current",
+			})
+			return
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return items, nil
 }
 
-func (v *VLC) Seek(seconds int) error {
-	url := fmt.Sprintf("http://%s:%d/requests/status.xml?command=seek&val=%d",
-		v.Host, v.Port, seconds)
+func (v *VLC) Enqueue(path string) error {
+	return v.command(url.Values{"command": {"in_enqueue"}, "input": {fileURI(path)}})
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-	req.SetBasicAuth("", v.Password)
+func (v *VLC) Clear() error {
+	return v.command(url.Values{"command": {"pl_empty"}})
+}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+func (v *VLC) Play() error {
+	return v.command(url.Values{"command": {"pl_play"}})
+}
 
-	return nil
+func (v *VLC) Seek(seconds int) error {
+	return v.command(url.Values{"command": {"seek"}, "val": {strconv.Itoa(seconds)}})
 }
@@ -0,0 +1,36 @@
+package vlc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yoooby/showtrack/internal/vlc/vlctest"
+)
+
+func TestStartAttachesToRunningVLC(t *testing.T) {
+	srv := vlctest.NewServer("secret")
+	defer srv.Close()
+
+	v := &VLC{Host: srv.Host(), Port: srv.Port(), Password: "secret"}
+	if err := v.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if v.attached == nil || v.cmd != nil {
+		t.Fatal("expected to attach to the running VLC instead of spawning one")
+	}
+	v.Quit()
+}
+
+func TestStartReportsWrongPassword(t *testing.T) {
+	srv := vlctest.NewServer("secret")
+	defer srv.Close()
+
+	v := &VLC{Host: srv.Host(), Port: srv.Port(), Password: "wrong"}
+	err := v.Start()
+	if err == nil || !strings.Contains(err.Error(), "rejected the password") {
+		t.Fatalf("expected a password error, got %v", err)
+	}
+	if v.cmd != nil {
+		t.Fatal("spawned a second VLC")
+	}
+}
@@ -0,0 +1,438 @@
+// Package vlctest provides a fake VLC HTTP interface for tests.
+//
+// The server understands the status.json, status.xml and playlist.json
+// requests Player sends, keeps a simulated playlist and only moves playback forward when
+// Advance is called, so tests control time instead of sleeping:
+//
+//	srv := vlctest.NewServer("secret")
+//	defer srv.Close()
+//	backend := &vlc.VLC{Host: srv.Host(), Port: srv.Port(), Password: "secret"}
+package vlctest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLength is the duration of playlist items without a length set by SetLength.
+const DefaultLength = 45 * 60
+
+// Item is an entry of the simulated playlist.
+type Item struct {
+	ID     int
+	URI    string
+	Length int // seconds
+}
+
+// Server is a fake VLC HTTP interface.
+type Server struct {
+	Password string
+
+	srv      *httptest.Server
+	mu       sync.Mutex
+	playlist []Item
+	current  int // index into playlist, -1 when nothing is playing
+	state    string
+	time     int
+	nextID   int
+	lengths  map[string]int
+	commands []string
+}
+
+// NewServer starts a fake VLC that requires the given HTTP password.
+func NewServer(password string) *Server {
+	s := &Server{
+		Password: password,
+		current:  -1,
+		state:    "stopped",
+		nextID:   1,
+		lengths:  make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/requests/status.json", s.handleStatusJSON)
+	mux.HandleFunc("/requests/status.xml", s.handleStatusXML)
+	mux.HandleFunc("/requests/playlist.json", s.handlePlaylistJSON)
+	s.srv = httptest.NewServer(s.auth(mux))
+	return s
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Host returns the host the server listens on.
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.srv.Listener.Addr().String())
+	return host
+}
+
+// Port returns the port the server listens on.
+func (s *Server) Port() int {
+	return s.srv.Listener.Addr().(*net.TCPAddr).Port
+}
+
+// SetLength sets the duration in seconds of the file at path, for items
+// enqueued after the call.
+func (s *Server) SetLength(path string, seconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lengths[path] = seconds
+}
+
+// Advance moves the clock forward. While playing, the current item advances
+// and playback continues with the next item once it reaches its end, or
+// stops after the last one.
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != "playing" {
+		return
+	}
+
+	s.time += int(d / time.Second)
+	for s.current >= 0 && s.time >= s.playlist[s.current].Length {
+		s.time -= s.playlist[s.current].Length
+		s.current++
+		if s.current >= len(s.playlist) {
+			s.stop()
+			return
+		}
+	}
+}
+
+// Next simulates the user skipping to the next playlist item.
+func (s *Server) Next() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current < 0 {
+		return
+	}
+	s.time = 0
+	s.current++
+	if s.current >= len(s.playlist) {
+		s.stop()
+	}
+}
+
+// PlayID simulates the user double clicking a playlist item.
+func (s *Server) PlayID(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.playID(id)
+}
+
+// Remove simulates the user deleting a playlist item.
+func (s *Server) Remove(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(id)
+}
+
+// Pause simulates the user toggling pause.
+func (s *Server) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.togglePause()
+}
+
+// Playlist returns a copy of the simulated playlist.
+func (s *Server) Playlist() []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Item(nil), s.playlist...)
+}
+
+// Current returns the item being played.
+func (s *Server) Current() (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current < 0 {
+		return Item{}, false
+	}
+	return s.playlist[s.current], true
+}
+
+// State returns "playing", "paused" or "stopped".
+func (s *Server) State() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Time returns the position in the current item, in seconds.
+func (s *Server) Time() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.time
+}
+
+// Commands returns every command received, in order, e.g. "in_enqueue" or "seek".
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// VLC ignores the user name and only checks the password
+		_, password, ok := r.BasicAuth()
+		if !ok || password != s.Password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="VLC stream"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStatusJSON(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.exec(r.URL.Query())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.status())
+}
+
+func (s *Server) handleStatusXML(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.exec(r.URL.Query())
+	st := s.status()
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(struct {
+		XMLName     xml.Name `xml:"root"`
+		State       string   `xml:"state"`
+		Time        int      `xml:"time"`
+		Length      int      `xml:"length"`
+		CurrentPlID int      `xml:"currentplid"`
+	}{State: st.State, Time: st.Time, Length: st.Length, CurrentPlID: st.CurrentPlID})
+}
+
+type playlistNode struct {
+	RO       string         `json:"ro"`
+	Type     string         `json:"type"`
+	Name     string         `json:"name"`
+	ID       string         `json:"id"`
+	Duration *int           `json:"duration,omitempty"`
+	URI      string         `json:"uri,omitempty"`
+	Current  string         `json:"current,omitempty"`
+	Children []playlistNode `json:"children,omitempty"`
+}
+
+func (s *Server) handlePlaylistJSON(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlist := playlistNode{RO: "ro", Type: "node", Name: "Playlist", ID: "1", Children: []playlistNode{}}
+	for i, item := range s.playlist {
+		duration := item.Length
+		leaf := playlistNode{
+			RO:       "rw",
+			Type:     "leaf",
+			Name:     path.Base(filePath(item.URI)),
+			ID:       strconv.Itoa(item.ID),
+			Duration: &duration,
+			URI:      item.URI,
+		}
+		if i == s.current {
+			leaf.Current = "current"
+		}
+		playlist.Children = append(playlist.Children, leaf)
+	}
+
+	root := playlistNode{
+		RO:   "rw",
+		Type: "node",
+		ID:   "0",
+		Children: []playlistNode{
+			playlist,
+			{RO: "ro", Type: "node", Name: "Media Library", ID: "2", Children: []playlistNode{}},
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(root)
+}
+
+// exec runs the command of a status request. Callers must hold s.mu.
+func (s *Server) exec(q url.Values) {
+	cmd := q.Get("command")
+	if cmd == "" {
+		return
+	}
+	s.commands = append(s.commands, cmd)
+
+	switch cmd {
+	case "in_enqueue":
+		s.enqueue(q.Get("input"))
+	case "in_play":
+		if q.Get("input") != "" {
+			s.enqueue(q.Get("input"))
+			s.playID(s.playlist[len(s.playlist)-1].ID)
+		}
+	case "pl_empty":
+		s.playlist = nil
+		s.stop()
+	case "pl_play":
+		if id, err := strconv.Atoi(q.Get("id")); err == nil {
+			s.playID(id)
+		} else if s.current >= 0 {
+			s.state = "playing"
+		} else if len(s.playlist) > 0 {
+			s.current, s.time, s.state = 0, 0, "playing"
+		}
+	case "pl_delete":
+		if id, err := strconv.Atoi(q.Get("id")); err == nil {
+			s.remove(id)
+		}
+	case "pl_pause":
+		s.togglePause()
+	case "pl_stop":
+		s.stop()
+	case "pl_next":
+		if s.current >= 0 {
+			s.time = 0
+			s.current++
+			if s.current >= len(s.playlist) {
+				s.stop()
+			}
+		}
+	case "seek":
+		s.seek(q.Get("val"))
+	}
+}
+
+func (s *Server) enqueue(input string) {
+	if input == "" {
+		return
+	}
+	length, ok := s.lengths[filePath(input)]
+	if !ok {
+		length = DefaultLength
+	}
+	s.playlist = append(s.playlist, Item{ID: s.nextID, URI: input, Length: length})
+	s.nextID++
+}
+
+func (s *Server) playID(id int) bool {
+	for i, item := range s.playlist {
+		if item.ID == id {
+			s.current, s.time, s.state = i, 0, "playing"
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) remove(id int) bool {
+	for i, item := range s.playlist {
+		if item.ID != id {
+			continue
+		}
+		s.playlist = append(s.playlist[:i], s.playlist[i+1:]...)
+		switch {
+		case i == s.current:
+			s.stop()
+		case i < s.current:
+			s.current--
+		}
+		return true
+	}
+	return false
+}
+
+func (s *Server) togglePause() {
+	switch s.state {
+	case "playing":
+		s.state = "paused"
+	case "paused":
+		s.state = "playing"
+	}
+}
+
+func (s *Server) stop() {
+	s.current, s.time, s.state = -1, 0, "stopped"
+}
+
+// seek handles absolute ("120") and relative ("+10", "-10") values in seconds.
+func (s *Server) seek(val string) {
+	if s.current < 0 {
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(val, "s"))
+	if err != nil {
+		return
+	}
+	if strings.HasPrefix(val, "+") || strings.HasPrefix(val, "-") {
+		n += s.time
+	}
+	s.time = max(0, min(n, s.playlist[s.current].Length))
+}
+
+type statusJSON struct {
+	State       string       `json:"state"`
+	Time        int          `json:"time"`
+	Length      int          `json:"length"`
+	Position    float64      `json:"position"`
+	CurrentPlID int          `json:"currentplid"`
+	Volume      int          `json:"volume"`
+	Rate        float64      `json:"rate"`
+	Fullscreen  bool         `json:"fullscreen"`
+	Information *information `json:"information,omitempty"`
+}
+
+type information struct {
+	Category struct {
+		Meta struct {
+			Filename string `json:"filename"`
+			Title    string `json:"title,omitempty"`
+		} `json:"meta"`
+	} `json:"category"`
+}
+
+func (s *Server) status() statusJSON {
+	st := statusJSON{
+		State:       s.state,
+		CurrentPlID: -1,
+		Volume:      256,
+		Rate:        1,
+	}
+	if s.current < 0 {
+		return st
+	}
+
+	item := s.playlist[s.current]
+	st.Time = s.time
+	st.Length = item.Length
+	st.CurrentPlID = item.ID
+	if item.Length > 0 {
+		st.Position = float64(s.time) / float64(item.Length)
+	}
+	st.Information = &information{}
+	st.Information.Category.Meta.Filename = path.Base(filePath(item.URI))
+	return st
+}
+
+// filePath turns a file:// URI back into a local path.
+func filePath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}